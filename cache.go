@@ -1,8 +1,10 @@
 package fscache
 
 import (
+	"context"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -11,16 +13,72 @@ import (
 type (
 	// KeyStoreData object
 	KeyStoreData struct {
-		Value    any
+		Value any
+		// Duration is the time the data object expires at, a zero time never expires
 		Duration time.Time
+		// SoftDuration is the time the data object turns stale at, a zero time never turns
+		// stale. A stale data object is served until it expires while it gets refreshed,
+		// see SetWithSoftTTL().
+		SoftDuration time.Time
+		// Version changes every time the data object is written, see GetData(). It is set
+		// by the KeyStore, the Version of the data objects passed to SetMany() is ignored.
+		Version uint64
+		// size is the estimated number of bytes the entry occupies
+		size int64
+		// softTTL and hardTTL are the time to live a refresh sets SoftDuration and Duration with,
+		// a zero hardTTL keeps Duration
+		softTTL, hardTTL time.Duration
+		// tags are the sorted tags the data object was set with, see SetWithTags()
+		tags []string
+		// sliding is the time to live a read resets, see SetSliding()
+		sliding time.Duration
+		// deadline is the time a data object with a sliding time to live expires at
+		// at the latest, a zero time has no deadline
+		deadline time.Time
 	}
 
-	// KeyStore object instance
+	// KeyStore object instance. The views of a KeyStore, see WithJitter(), hold the same
+	// storage and only have their own settings of the writes.
 	KeyStore struct {
-		mu     *sync.RWMutex
+		*keyStore
+		// jitter spreads the time to live of Set(), SetMany() and OverWrite()
+		jitter *atomic.Pointer[TTLJitter]
+		// sliding makes the writes of a WithSliding() view set a sliding time to live
+		sliding slidingWrites
+	}
+
+	// keyStore is the storage of a KeyStore, shared with its views
+	keyStore struct {
 		logger zerolog.Logger
-		// storage for key value pair storage
-		storage []map[string]KeyStoreData
+		// shards partitions the key value pair storage, each shard is guarded by its own lock
+		shards []*keyStoreShard
+		// usage tracks the entries and bytes held by the storage and the limits on them
+		usage *keyStoreUsage
+		// expiry holds the settings of the active expiry cycle
+		expiry *keyStoreExpiry
+		// clock tells the time data objects are set and expire at
+		clock Clock
+		// waiters wakes up the callers blocked on keys, like BLPop()
+		waiters *keyWaiters
+		// events delivers the changes of keys to the watchers
+		events *notifier
+		// versions hands out the versions of the data objects, it only ever grows so that
+		// a key deleted and set again doesn't get a version it had before
+		versions *atomic.Uint64
+		// loads deduplicates the concurrent GetOrLoad() calls and caches their errors
+		loads *loadGroup
+		// refresh holds the functions refreshing the stale data objects
+		refresh *keyStoreRefresh
+		// lifecycle tracks the background refreshes so that closing the cache stops them
+		lifecycle *lifecycle
+		// tags indexes the keys of the data objects set with tags
+		tags *tagIndex
+		// evictions runs the OnEvict() callbacks with the values leaving the storage
+		evictions *evictHooks
+		// sizes estimates the size of the values, it is shared with the DataStore
+		sizes *sizeEstimator
+		// stats counts the operations on the storage, see Cache.Stats()
+		stats *keyStoreStats
 	}
 
 	// DataStore represents the in-memory store for documents (key-value pairs)
@@ -30,6 +88,20 @@ type (
 		indexes map[string]map[string]map[any][]int // Indexes for fast querying
 		schemas map[string]Schema                   // Schema for validation
 		mu      *sync.RWMutex                       // Mutex for thread safety
+		// persistPath is the JSON file data is persisted into and loaded from
+		persistPath string
+		// clock tells the time documents are created and updated at and drives the Sync() workers
+		clock Clock
+		// lifecycle tracks the Sync() workers so that closing the cache stops them
+		lifecycle *lifecycle
+		// events delivers the changes of documents to the watchers
+		events *notifier
+		// sizes estimates the size of the documents, it is shared with the KeyStore
+		sizes *sizeEstimator
+		// stats counts the queries of the namespaces, see Cache.Stats()
+		stats *dataStoreStats
+		// observers are told about the queries, syncs and persistence, see Cache.Observe()
+		observers *observers
 	}
 
 	// Schema represents the structure of a document with type validation
@@ -40,6 +112,25 @@ type (
 		logger            zerolog.Logger
 		KeyStoreInstance  KeyStore
 		DataStoreInstance DataStore
+		// cleanupInterval is how often the runner performs its maintenance tasks
+		cleanupInterval time.Duration
+		// clock drives the runner
+		clock Clock
+		// lifecycle tracks the background goroutines of the cache
+		lifecycle *lifecycle
+		// pubsub routes the messages published to the subscribers
+		pubsub *pubSub
+		// events delivers the changes of the KeyStore and the DataStore to the watchers
+		events *notifier
+	}
+
+	// lifecycle tracks the background goroutines of a cache so that they can be stopped
+	// and waited for when the cache is closed
+	lifecycle struct {
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		done    chan struct{}
+		stopped bool
 	}
 
 	// Operations lists all available operations on the fs-cache
@@ -51,41 +142,152 @@ type (
 		KeyStore() *KeyStore
 		// DataStore gives you a MongoDB-like feature similarly as you would with a MondoDB database
 		DataStore() *DataStore
+
+		// Publish() sends a message to the subscribers of a channel
+		Publish(channel string, msg any) int
+		// Subscribe() returns the messages published to channels until ctx is done
+		Subscribe(ctx context.Context, channels ...string) <-chan Message
+		// PSubscribe() returns the messages published to the channels matching patterns until ctx is done
+		PSubscribe(ctx context.Context, patterns ...string) <-chan Message
+		// Watch() returns the changes of the KeyStore keys and the DataStore documents until ctx is done
+		Watch(ctx context.Context, filter EventFilter) <-chan Event
+
+		// Stats() returns the hits, misses, writes and evictions of the KeyStore and the namespaces statistics
+		Stats() Stats
+		// ResetStats() sets the counters of the statistics back to zero
+		ResetStats()
+		// Observe() registers an observer of the DataStore queries, syncs and persistence
+		Observe(observer Observer)
+
+		// Close() stops the background jobs of the cache and waits for them to exit
+		Close(ctx context.Context) error
 	}
 )
 
-// New initializes an instance of the in-memory storage cache
-func New() Operations {
-	logger := zerolog.New(io.Discard)
+// New initializes an instance of the in-memory storage cache.
+// The cache can be configured with options such as WithCapacity() or WithLogger().
+func New(opts ...Option) Operations {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	logger := cfg.logger
 	mu := &sync.RWMutex{}
+	lc := newLifecycle()
+	events := newNotifier(cfg.pubSubBufferSize, cfg.slowConsumerPolicy)
 
-	ks := KeyStore{
-		mu:      mu,
-		logger:  logger,
-		storage: make([]map[string]KeyStoreData, 0),
+	ks := newKeyStore(logger)
+	ks.clock = cfg.clock
+	ks.events = events
+	ks.lifecycle = lc
+	ks.SetActiveExpiry(cfg.activeExpiryInterval, cfg.activeExpirySamples)
+	if cfg.evictionPolicy != nil {
+		ks.SetEvictionPolicy(cfg.evictionPolicy)
 	}
+	ks.SetCapacity(cfg.maxEntries, cfg.maxBytes)
+	ks.SetNegativeCaching(cfg.negativeTTL)
+	ks.SetTTLJitter(cfg.ttlJitter)
+	ks.SetSizeEstimator(cfg.sizeEstimator)
 
 	ds := DataStore{
-		logger:  logger,
-		mu:      mu,
-		data:    make(map[string][]map[string]any),
-		indexes: make(map[string]map[string]map[any][]int),
-		schemas: make(map[string]Schema),
+		logger:      logger,
+		mu:          mu,
+		data:        make(map[string][]map[string]any),
+		indexes:     make(map[string]map[string]map[any][]int),
+		schemas:     make(map[string]Schema),
+		persistPath: cfg.persistPath,
+		clock:       cfg.clock,
+		lifecycle:   lc,
+		events:      events,
+		sizes:       ks.sizes,
+		stats:       &dataStoreStats{},
+		observers:   &observers{},
 	}
 
 	ch := Cache{
 		logger:            logger,
 		KeyStoreInstance:  ks,
 		DataStoreInstance: ds,
+		cleanupInterval:   cfg.cleanupInterval,
+		clock:             cfg.clock,
+		lifecycle:         lc,
+		pubsub:            newPubSub(cfg.pubSubBufferSize, cfg.slowConsumerPolicy),
+		events:            events,
 	}
 
 	// start go routine
-	go ch.runner()
+	lc.goroutine(ch.runner)
 
 	op := Operations(&ch)
 	return op
 }
 
+// Close() stops the runner and the Sync() workers of the cache and waits for them to exit.
+// The Pub/Sub subscriptions and the watches end and their channels are closed.
+// The Sync() workers synchronize the documents created since their last run before exiting,
+// and the DataStore data is persisted a last time if persistence is enabled.
+// If ctx is done before the background jobs exit, Close() returns the context error.
+// Calling Close() more than once is safe.
+func (c *Cache) Close(ctx context.Context) error {
+	c.lifecycle.stop()
+
+	exited := make(chan struct{})
+	go func() {
+		c.lifecycle.wg.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if persistDataStoreData {
+		return c.DataStoreInstance.Persist()
+	}
+
+	return nil
+}
+
+// newLifecycle returns a lifecycle with no goroutine running
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		done: make(chan struct{}),
+	}
+}
+
+// goroutine runs fn in a tracked goroutine, fn must return once done is closed.
+// It reports false without running fn if the lifecycle is already stopped.
+func (l *lifecycle) goroutine(fn func(done <-chan struct{})) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		return false
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		fn(l.done)
+	}()
+
+	return true
+}
+
+// stop signals the tracked goroutines to return
+func (l *lifecycle) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.stopped {
+		l.stopped = true
+		close(l.done)
+	}
+}
+
 // Debug() enables debug to get certain logs
 func (c *Cache) Debug(w io.Writer) {
 	logger := zerolog.New(w).With().Timestamp().Logger()
@@ -105,46 +307,49 @@ func (c *Cache) DataStore() *DataStore {
 }
 
 // runner is a method of the Cache struct that periodically performs maintenance tasks.
-// It runs a cron job every 30 seconds to:
+// It runs a cron job every cleanup interval (30 seconds by default) to:
 // 1. Log the execution of the cron job.
-// 2. Persist data if the persistDataStoreData flag is set.
-// 3. Lock the KeyStore, check for expired data objects, and remove them from the storage.
+// 2. Drop the expired loader errors cached by GetOrLoad().
+// 3. Persist data if the persistDataStoreData flag is set.
+//
+// In between, it runs the KeyStore active expiry cycle at the rate set with SetActiveExpiry(),
+// which samples keys having a duration and removes the expired ones, and refreshes the stale keys.
 //
-// The method uses a ticker to trigger the cron job at regular intervals and ensures
-// that the ticker is stopped when the method exits.
-func (ch *Cache) runner() {
-	ticker := time.NewTicker(30 * time.Second)
+// The method uses tickers of the cache clock to trigger the jobs at regular intervals,
+// returns once done is closed and ensures that the tickers are stopped when the method exits.
+func (ch *Cache) runner(done <-chan struct{}) {
+	ticker := ch.clock.NewTicker(ch.cleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		ch.logger.Info().Msg("cron job running...")
+	interval := ch.KeyStoreInstance.activeExpiryInterval()
+	expiryTicker := ch.clock.NewTicker(interval)
+	defer expiryTicker.Stop()
 
-		// Persist data if necessary
-		if persistDataStoreData {
-			if err := ch.DataStoreInstance.Persist(); err != nil {
-				ch.logger.Info().Msgf("persist error: %v", err)
-			}
-		}
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+			ch.logger.Info().Msg("cron job running...")
 
-		ch.KeyStore().mu.Lock()
-		defer ch.KeyStore().mu.Unlock()
+			// drop the loader errors cached by GetOrLoad() which are over
+			ch.KeyStoreInstance.loads.purge(ch.clock.Now())
 
-		var toRemove []int
-
-		currentTime := time.Now()
-
-		for i := 0; i < len(ch.KeyStoreInstance.storage); i++ {
-			for _, value := range ch.KeyStoreInstance.storage[i] {
-				if currentTime.Before(value.Duration) {
-					toRemove = append(toRemove, i)
-					ch.logger.Info().Msgf("data object [%v] got expired", ch.KeyStoreInstance.storage[i])
-					break
+			// Persist data if necessary
+			if persistDataStoreData {
+				if err := ch.DataStoreInstance.Persist(); err != nil {
+					ch.logger.Info().Msgf("persist error: %v", err)
 				}
 			}
-		}
+		case now := <-expiryTicker.C():
+			ch.KeyStoreInstance.activeExpireCycle(now)
+			ch.KeyStoreInstance.refreshAhead(now)
 
-		for _, index := range toRemove {
-			ch.KeyStoreInstance.storage = append(ch.KeyStoreInstance.storage[:index], ch.KeyStoreInstance.storage[index+1:]...)
+			// pick up a rate changed with SetActiveExpiry()
+			if next := ch.KeyStoreInstance.activeExpiryInterval(); next != interval {
+				interval = next
+				expiryTicker.Reset(interval)
+			}
 		}
 	}
 }
@@ -0,0 +1,135 @@
+package fscache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fixedClock is a Clock always telling the same time, its tickers are real ones
+type fixedClock struct {
+	systemClock
+	now time.Time
+}
+
+// Now returns the fixed time
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+// manualClock is a Clock whose time only moves with Advance(), its tickers are real ones.
+// The tests of this package can't use the fscachetest clock, which imports the package.
+type manualClock struct {
+	systemClock
+	mu  sync.Mutex
+	now time.Time
+}
+
+// newManualClock returns a manualClock telling now
+func newManualClock(now time.Time) *manualClock {
+	return &manualClock{now: now}
+}
+
+// Now returns the current manual time
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the time forward by d
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestNewOptions(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	clock := fixedClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	fs := New(
+		WithLogger(zerolog.New(buf)),
+		WithClock(clock),
+		WithCapacity(2, 0),
+		WithEvictionPolicy(NewFIFOPolicy()),
+		WithCleanupInterval(time.Hour),
+		WithActiveExpiry(time.Second, 10),
+		WithPersistPath(filepath.Join(t.TempDir(), "storage.json")),
+	)
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	ks := fs.KeyStore()
+	require.NoError(t, ks.Set("key1", 1, time.Minute))
+	require.NoError(t, ks.Set("key2", 2, time.Minute))
+	_, err := ks.Get("key1")
+	require.NoError(t, err)
+	require.NoError(t, ks.Set("key3", 3, time.Minute))
+
+	assert.ElementsMatch(t, []string{"key2", "key3"}, ks.Keys())
+	assert.Contains(t, buf.String(), "got evicted")
+
+	ttl, err := ks.PTTL("key3")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl) // the clock doesn't move
+
+	assert.Equal(t, time.Second, ks.activeExpiryInterval())
+	assert.Equal(t, time.Hour, fs.(*Cache).cleanupInterval)
+}
+
+func TestWithPersistPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	fs := New(WithPersistPath(path))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	require.NoError(t, fs.DataStore().Collection("user").Insert(map[string]any{"name": "Jane Doe"}))
+	require.NoError(t, fs.DataStore().Persist())
+
+	_, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, fs.DataStore().LoadDefault())
+}
+
+func TestClose(t *testing.T) {
+	fs := New(
+		WithCleanupInterval(time.Millisecond),
+		WithPersistPath(filepath.Join(t.TempDir(), "storage.json")),
+	)
+
+	ns := fs.DataStore().Namespace("user")
+	ns.ConnectSQLDB(&gorm.DB{}).Sync(time.Hour)
+
+	require.NoError(t, fs.Close(context.Background()))
+	require.NoError(t, fs.Close(context.Background())) // closing twice is safe
+
+	// workers can't be started once the cache is closed
+	assert.False(t, fs.(*Cache).lifecycle.goroutine(func(<-chan struct{}) {}))
+}
+
+func TestCloseContext(t *testing.T) {
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+
+	release := make(chan struct{})
+	fs.(*Cache).lifecycle.goroutine(func(done <-chan struct{}) {
+		<-done
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, fs.Close(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, fs.Close(context.Background()))
+}
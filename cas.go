@@ -0,0 +1,148 @@
+package fscache
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrVersionMismatch the data object changed since the version it was read at
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// GetData() retrieves a data object from the in-memory storage with its duration and version.
+// Pass the version to OverWriteIfVersion() to update the data only if nobody changed it since.
+func (ks *KeyStore) GetData(key string) (KeyStoreData, error) {
+	data, ok := ks.peek(key)
+	ks.stats.lookup(ok)
+	if !ok {
+		ks.expire(key)
+		return KeyStoreData{}, ErrKeyNotFound
+	}
+
+	ks.access(key)
+
+	return data, nil
+}
+
+// OverWriteIfVersion() updates an already set value only if its version is still the given
+// one, see GetData(). It fails with ErrVersionMismatch if the data changed in the meantime.
+func (ks *KeyStore) OverWriteIfVersion(key string, version uint64, value any, duration ...time.Duration) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	prev, ok := ks.live(shard, key, now)
+	if !ok {
+		shard.mu.Unlock()
+		return ErrKeyNotFound
+	}
+
+	if prev.Version != version {
+		shard.mu.Unlock()
+		return ErrVersionMismatch
+	}
+
+	ks.store(shard, key, ks.slidingWrite(now, KeyStoreData{
+		Value:    value,
+		Duration: expiresAt(now, duration),
+	}))
+	ks.notify(EventOverWrite, key, prev.Value, value)
+	ks.evicted(key, prev.Value, EvictReplaced)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// CompareAndSwap() atomically replaces the value stored at key with newValue if the current
+// value equals oldValue, as compared by reflect.DeepEqual(), and reports whether it did.
+// The data keeps its time to live.
+func (ks *KeyStore) CompareAndSwap(key string, oldValue, newValue any) (bool, error) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		shard.mu.Unlock()
+		return false, ErrKeyNotFound
+	}
+
+	prev := data.Value
+	if !reflect.DeepEqual(exposed(prev), oldValue) {
+		shard.mu.Unlock()
+		return false, nil
+	}
+
+	data.Value = newValue
+	ks.store(shard, key, data)
+	ks.notify(EventOverWrite, key, prev, newValue)
+	ks.evicted(key, prev, EvictReplaced)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return true, nil
+}
+
+// SetNX() adds a new data only if the key doesn't exist and reports whether it did, see Set()
+func (ks *KeyStore) SetNX(key string, value any, duration ...time.Duration) bool {
+	return ks.Set(key, value, duration...) == nil
+}
+
+// SetXX() updates a data only if the key exists and reports whether it did, see OverWrite()
+func (ks *KeyStore) SetXX(key string, value any, duration ...time.Duration) bool {
+	return ks.OverWrite(key, value, duration...) == nil
+}
+
+// GetSet() sets the value of a key and returns its previous value. A missing key is set as
+// well, its previous value is nil along with ErrKeyNotFound. The optional duration sets the
+// time to live like Set().
+func (ks *KeyStore) GetSet(key string, value any, duration ...time.Duration) (any, error) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	prev, existed := ks.live(shard, key, now)
+	ks.store(shard, key, ks.slidingWrite(now, KeyStoreData{
+		Value:    value,
+		Duration: expiresAt(now, duration),
+	}))
+
+	var old any
+	if existed {
+		old = exposed(prev.Value)
+		ks.notify(EventOverWrite, key, prev.Value, value)
+		ks.evicted(key, prev.Value, EvictReplaced)
+	} else {
+		ks.notify(EventSet, key, nil, value)
+	}
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	if !existed {
+		return nil, ErrKeyNotFound
+	}
+
+	return old, nil
+}
+
+// GetDel() deletes a data and returns its value
+func (ks *KeyStore) GetDel(key string) (any, error) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	value := exposed(data.Value)
+	ks.remove(shard, key)
+	ks.notify(EventDel, key, data.Value, nil)
+	ks.evicted(key, data.Value, EvictDeleted)
+
+	return value, nil
+}
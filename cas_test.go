@@ -0,0 +1,164 @@
+package fscache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetData(t *testing.T) {
+	ks := newTestKeyStore()
+
+	data, err := ks.GetData("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "value1", data.Value)
+	assert.False(t, data.Duration.IsZero())
+	assert.NotZero(t, data.Version)
+
+	// every write changes the version, even back to the same value
+	require.NoError(t, ks.OverWrite("key1", "value1", time.Minute))
+	updated, err := ks.GetData("key1")
+	require.NoError(t, err)
+	assert.Greater(t, updated.Version, data.Version)
+
+	// a deleted and recreated key never gets its old version back
+	require.NoError(t, ks.Del("key1"))
+	require.NoError(t, ks.Set("key1", "value1"))
+	recreated, err := ks.GetData("key1")
+	require.NoError(t, err)
+	assert.Greater(t, recreated.Version, updated.Version)
+
+	_, err = ks.GetData("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestOverWriteIfVersion(t *testing.T) {
+	ks := newTestKeyStore()
+
+	data, err := ks.GetData("key2")
+	require.NoError(t, err)
+
+	require.NoError(t, ks.OverWriteIfVersion("key2", data.Version, 11))
+	assert.ErrorIs(t, ks.OverWriteIfVersion("key2", data.Version, 12), ErrVersionMismatch)
+	assert.ErrorIs(t, ks.OverWriteIfVersion("missing", data.Version, 12), ErrKeyNotFound)
+
+	value, err := ks.Get("key2")
+	require.NoError(t, err)
+	assert.Equal(t, 11, value)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	ks := newTestKeyStore()
+	ks.clock = fixedClock{now: keyStoreTestCases[0]["key1"].Duration.Add(-time.Minute)}
+
+	swapped, err := ks.CompareAndSwap("key1", "other", "value2")
+	require.NoError(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = ks.CompareAndSwap("key1", "value1", "value2")
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	value, err := ks.Get("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "value2", value)
+
+	// the time to live is kept
+	ttl, err := ks.TTL("key1")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	// values are compared deeply
+	_, err = ks.RPush("list", "a", "b")
+	require.NoError(t, err)
+	swapped, err = ks.CompareAndSwap("list", []any{"a", "b"}, "flattened")
+	require.NoError(t, err)
+	assert.True(t, swapped)
+
+	_, err = ks.CompareAndSwap("missing", nil, "value")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	ks := newTestKeyStore()
+	require.NoError(t, ks.Set("counter", 0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for {
+					value, err := ks.Get("counter")
+					require.NoError(t, err)
+
+					swapped, err := ks.CompareAndSwap("counter", value, value.(int)+1)
+					require.NoError(t, err)
+					if swapped {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := ks.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, 800, value)
+}
+
+func TestSetNXSetXX(t *testing.T) {
+	ks := newTestKeyStore()
+
+	assert.False(t, ks.SetNX("key1", "other"))
+	assert.True(t, ks.SetNX("key4", "value4"))
+	assert.True(t, ks.SetXX("key4", "updated", time.Second))
+	assert.False(t, ks.SetXX("missing", "value"))
+
+	value, err := ks.Get("key4")
+	require.NoError(t, err)
+	assert.Equal(t, "updated", value)
+
+	_, err = ks.Get("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestGetSet(t *testing.T) {
+	ks := newTestKeyStore()
+
+	prev, err := ks.GetSet("key2", 20)
+	require.NoError(t, err)
+	assert.Equal(t, 10, prev)
+
+	value, err := ks.Get("key2")
+	require.NoError(t, err)
+	assert.Equal(t, 20, value)
+
+	// a missing key is set anyway
+	prev, err = ks.GetSet("key4", "value4", time.Minute)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Nil(t, prev)
+
+	value, err = ks.Get("key4")
+	require.NoError(t, err)
+	assert.Equal(t, "value4", value)
+}
+
+func TestGetDel(t *testing.T) {
+	ks := newTestKeyStore()
+
+	value, err := ks.GetDel("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "value1", value)
+
+	_, err = ks.Get("key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = ks.GetDel("key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
@@ -0,0 +1,58 @@
+package fscache
+
+import "time"
+
+type (
+	// Clock tells the time to the cache and drives its background jobs. It defaults to
+	// the system clock and can be replaced with WithClock(), e.g. with the fake clock of
+	// the fscachetest package to control the time in tests.
+	Clock interface {
+		// Now returns the current time
+		Now() time.Time
+		// NewTicker returns a Ticker sending the current time on its channel every d
+		NewTicker(d time.Duration) Ticker
+	}
+
+	// Ticker delivers ticks at intervals, see time.Ticker
+	Ticker interface {
+		// C returns the channel the ticks are delivered on
+		C() <-chan time.Time
+		// Stop turns off the ticker, no more ticks are sent
+		Stop()
+		// Reset stops the ticker and resets its period to d
+		Reset(d time.Duration)
+	}
+
+	// systemClock is the Clock backed by the time package
+	systemClock struct{}
+
+	// systemTicker is the Ticker backed by a time.Ticker
+	systemTicker struct {
+		ticker *time.Ticker
+	}
+)
+
+// Now returns the current local time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker returns a Ticker backed by a time.Ticker
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+// C returns the channel the ticks are delivered on
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop turns off the ticker
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// Reset stops the ticker and resets its period to d
+func (t systemTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
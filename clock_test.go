@@ -0,0 +1,77 @@
+package fscache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	fscache "github.com/jiyamathias/fs-cache"
+	"github.com/jiyamathias/fs-cache/fscachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeClockCache returns a cache driven by a fake clock, once its runner started
+func newFakeClockCache(t *testing.T) (fscache.Operations, *fscachetest.Clock, string) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	clock := fscachetest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	fs := fscache.New(fscache.WithClock(clock), fscache.WithPersistPath(path))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	// wait for the cleanup and active expiry tickers of the runner
+	clock.BlockUntil(2)
+
+	return fs, clock, path
+}
+
+func TestFakeClockTTL(t *testing.T) {
+	fs, clock, _ := newFakeClockCache(t)
+
+	require.NoError(t, fs.KeyStore().Set("key", "value", time.Minute))
+
+	clock.Advance(59 * time.Second)
+	ttl, err := fs.KeyStore().TTL("key")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, ttl)
+
+	clock.Advance(time.Second)
+	_, err = fs.KeyStore().Get("key")
+	require.ErrorIs(t, err, fscache.ErrKeyNotFound)
+}
+
+func TestFakeClockActiveExpiry(t *testing.T) {
+	fs, clock, _ := newFakeClockCache(t)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, fs.KeyStore().Set("key"+strconv.Itoa(i), i, time.Minute))
+	}
+	require.NoError(t, fs.KeyStore().Set("persistent", "value"))
+
+	clock.Advance(2 * time.Minute)
+	assert.Eventually(t, func() bool {
+		return fs.KeyStore().Size() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestFakeClockPersistence(t *testing.T) {
+	fs, clock, path := newFakeClockCache(t)
+
+	require.NoError(t, fs.DataStore().Collection("user").Insert(map[string]any{"name": "Clock Doe"}))
+
+	// enables persistence, then let the runner persist on its own
+	require.NoError(t, fs.DataStore().Persist())
+	require.NoError(t, os.Remove(path))
+
+	clock.Advance(30 * time.Second)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	record, err := fs.DataStore().Collection("user").Filter(map[string]any{"name": "Clock Doe"}).First()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", record["createdAt"])
+}
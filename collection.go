@@ -0,0 +1,113 @@
+package fscache
+
+import "errors"
+
+// ErrWrongType operation against a key holding the wrong kind of value
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// collection is implemented by the values of the Redis-like data types, like lists.
+// Collections are mutated in place under the lock of their shard, so they are never
+// handed out to callers: the read paths return a snapshot of them instead.
+type collection interface {
+	// len returns the number of elements, an empty collection is removed from the storage
+	len() int
+	// snapshot returns a copy of the collection made of plain Go values
+	snapshot() any
+	// memSize returns an estimate of the number of bytes held by the collection. It is
+	// maintained as elements come and go so storing a large collection stays cheap.
+	memSize() int64
+}
+
+// exposed returns the value to hand out to callers: a snapshot for a collection,
+// the value itself otherwise. The caller must hold the lock of the shard.
+func exposed(value any) any {
+	if c, ok := value.(collection); ok {
+		return c.snapshot()
+	}
+
+	return value
+}
+
+// readCollection calls fn with the collection of type C stored under key while holding the
+// read lock of its shard. fn is not called if the key doesn't exist, ErrWrongType is returned
+// if the key holds another kind of value.
+func readCollection[C collection](ks *KeyStore, key string, fn func(c C)) error {
+	shard := ks.shard(key)
+	shard.mu.RLock()
+
+	data, ok := shard.items[key]
+	if !ok || data.expired(ks.clock.Now()) {
+		shard.mu.RUnlock()
+		ks.stats.lookup(false)
+		return nil
+	}
+
+	c, ok := data.Value.(C)
+	if !ok {
+		shard.mu.RUnlock()
+		return ErrWrongType
+	}
+
+	fn(c)
+	shard.mu.RUnlock()
+
+	ks.stats.lookup(true)
+	ks.access(key)
+
+	return nil
+}
+
+// updateCollection calls fn with the collection of type C stored under key while holding the
+// write lock of its shard. A missing key gets a collection built by create which never expires,
+// or fails with ErrKeyNotFound if create is nil. Once fn succeeds the collection is saved back,
+// or the key is removed if the collection is empty. fn must leave the collection untouched
+// when it fails.
+func updateCollection[C collection](ks *KeyStore, key string, create func() C, fn func(c C) error) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		if create == nil {
+			shard.mu.Unlock()
+			return ErrKeyNotFound
+		}
+		data = KeyStoreData{Value: create()}
+	}
+
+	c, isC := data.Value.(C)
+	if !isC {
+		shard.mu.Unlock()
+		return ErrWrongType
+	}
+
+	// fn updates the collection in place, the watchers get the value it had before
+	var prev any
+	if ok && ks.events.enabled() {
+		prev = c.snapshot()
+	}
+
+	if err := fn(c); err != nil {
+		shard.mu.Unlock()
+		return err
+	}
+
+	switch {
+	case c.len() == 0:
+		if ok {
+			ks.remove(shard, key)
+			ks.notify(EventDel, key, prev, nil)
+		}
+	case ok:
+		ks.store(shard, key, data)
+		ks.notify(EventOverWrite, key, prev, c)
+	default:
+		ks.store(shard, key, data)
+		ks.notify(EventSet, key, nil, c)
+	}
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package fscache
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+var (
+	// ErrNotInteger value is not an integer
+	ErrNotInteger = errors.New("value is not an integer")
+	// ErrNotNumeric value is not a number
+	ErrNotNumeric = errors.New("value is not a number")
+	// ErrOverflow increment or decrement would overflow
+	ErrOverflow = errors.New("increment or decrement would overflow")
+)
+
+// Incr() increments the integer stored at key by one, see IncrBy()
+func (ks *KeyStore) Incr(key string) (int64, error) {
+	return ks.IncrBy(key, 1)
+}
+
+// Decr() decrements the integer stored at key by one, see IncrBy()
+func (ks *KeyStore) Decr(key string) (int64, error) {
+	return ks.IncrBy(key, -1)
+}
+
+// DecrBy() decrements the integer stored at key by delta, see IncrBy()
+func (ks *KeyStore) DecrBy(key string, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	return ks.IncrBy(key, -delta)
+}
+
+// IncrBy() atomically increments the integer stored at key by delta and returns the result.
+// A missing key is created as an int64 set to delta which never expires, an existing key keeps
+// its time to live and its type: any integer type or a string holding an integer.
+// It fails with ErrNotInteger for other values and with ErrOverflow if the result doesn't
+// fit the type of the value.
+func (ks *KeyStore) IncrBy(key string, delta int64) (int64, error) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		data = KeyStoreData{Value: int64(0)}
+	}
+	prev := data.Value
+
+	value, result, err := addInt(data.Value, delta)
+	if err != nil {
+		shard.mu.Unlock()
+		return 0, err
+	}
+
+	data.Value = value
+	ks.store(shard, key, data)
+	if ok {
+		ks.notify(EventOverWrite, key, prev, value)
+	} else {
+		ks.notify(EventSet, key, nil, value)
+	}
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return result, nil
+}
+
+// IncrByFloat() atomically increments the number stored at key by delta and returns the result.
+// A missing key is created as a float64 set to delta which never expires, an existing key keeps
+// its time to live. A float32 or a string holding a number keeps its type, other numbers are
+// stored as a float64. It fails with ErrNotNumeric for other values and with ErrOverflow
+// if the result is not a finite number.
+func (ks *KeyStore) IncrByFloat(key string, delta float64) (float64, error) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		data = KeyStoreData{Value: float64(0)}
+	}
+	prev := data.Value
+
+	value, result, err := addFloat(data.Value, delta)
+	if err != nil {
+		shard.mu.Unlock()
+		return 0, err
+	}
+
+	data.Value = value
+	ks.store(shard, key, data)
+	if ok {
+		ks.notify(EventOverWrite, key, prev, value)
+	} else {
+		ks.notify(EventSet, key, nil, value)
+	}
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return result, nil
+}
+
+// addInt adds delta to an integer value and returns the sum in the type of the value along
+// with the sum as an int64
+func addInt(value any, delta int64) (any, int64, error) {
+	if _, ok := value.(collection); ok {
+		return nil, 0, ErrWrongType
+	}
+
+	if s, ok := value.(string); ok {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, 0, ErrNotInteger
+		}
+
+		sum, ok := addInt64(n, delta)
+		if !ok {
+			return nil, 0, ErrOverflow
+		}
+
+		return strconv.FormatInt(sum, 10), sum, nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sum, ok := addInt64(v.Int(), delta)
+		if !ok || v.OverflowInt(sum) {
+			return nil, 0, ErrOverflow
+		}
+
+		result := reflect.New(v.Type()).Elem()
+		result.SetInt(sum)
+		return result.Interface(), sum, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, 0, ErrOverflow
+		}
+
+		sum, ok := addInt64(int64(v.Uint()), delta)
+		if !ok || sum < 0 || v.OverflowUint(uint64(sum)) {
+			return nil, 0, ErrOverflow
+		}
+
+		result := reflect.New(v.Type()).Elem()
+		result.SetUint(uint64(sum))
+		return result.Interface(), sum, nil
+	default:
+		return nil, 0, ErrNotInteger
+	}
+}
+
+// addInt64 adds two int64 and reports whether the sum didn't overflow
+func addInt64(a, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+
+	return sum, true
+}
+
+// addFloat adds delta to a numeric value and returns the sum to store along with
+// the sum as a float64
+func addFloat(value any, delta float64) (any, float64, error) {
+	var n float64
+	switch v := value.(type) {
+	case collection:
+		return nil, 0, ErrWrongType
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, 0, ErrNotNumeric
+		}
+		n = parsed
+	case float32:
+		sum := float64(v) + delta
+		if math.IsInf(sum, 0) || math.IsNaN(sum) || math.Abs(sum) > math.MaxFloat32 {
+			return nil, 0, ErrOverflow
+		}
+		return float32(sum), float64(float32(sum)), nil
+	default:
+		rv := reflect.ValueOf(value)
+		if !isNumber(rv.Kind()) {
+			return nil, 0, ErrNotNumeric
+		}
+		n = rv.Convert(reflect.TypeOf(float64(0))).Float()
+	}
+
+	sum := n + delta
+	if math.IsInf(sum, 0) || math.IsNaN(sum) {
+		return nil, 0, ErrOverflow
+	}
+
+	if _, ok := value.(string); ok {
+		return strconv.FormatFloat(sum, 'f', -1, 64), sum, nil
+	}
+
+	return sum, sum, nil
+}
@@ -0,0 +1,146 @@
+package fscache
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrBy(t *testing.T) {
+	ks := newTestKeyStore()
+
+	// a missing key is created
+	value, err := ks.Incr("counter")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, value)
+
+	value, err = ks.IncrBy("counter", 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, value)
+
+	value, err = ks.Decr("counter")
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, value)
+
+	value, err = ks.DecrBy("counter", 15)
+	require.NoError(t, err)
+	assert.EqualValues(t, -5, value)
+
+	stored, err := ks.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-5), stored)
+
+	// key2 holds an int, it stays an int
+	value, err = ks.IncrBy("key2", 5)
+	require.NoError(t, err)
+	assert.EqualValues(t, 15, value)
+	stored, err = ks.Get("key2")
+	require.NoError(t, err)
+	assert.Equal(t, 15, stored)
+}
+
+func TestIncrByTypes(t *testing.T) {
+	testCases := map[string]struct {
+		value    any
+		delta    int64
+		expected any
+		err      error
+	}{
+		"int8":              {value: int8(1), delta: 1, expected: int8(2)},
+		"int8 overflow":     {value: int8(127), delta: 1, err: ErrOverflow},
+		"uint":              {value: uint(1), delta: 1, expected: uint(2)},
+		"uint below zero":   {value: uint(0), delta: -1, err: ErrOverflow},
+		"int64 overflow":    {value: int64(math.MaxInt64), delta: 1, err: ErrOverflow},
+		"string":            {value: "41", delta: 1, expected: "42"},
+		"string not number": {value: "abc", delta: 1, err: ErrNotInteger},
+		"float":             {value: 4.2, delta: 1, err: ErrNotInteger},
+		"bool":              {value: true, delta: 1, err: ErrNotInteger},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ks := newKeyStore(zerolog.Nop())
+			require.NoError(t, ks.Set("key", testCase.value))
+
+			_, err := ks.IncrBy("key", testCase.delta)
+			if testCase.err != nil {
+				require.ErrorIs(t, err, testCase.err)
+				return
+			}
+
+			require.NoError(t, err)
+			stored, err := ks.Get("key")
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, stored)
+		})
+	}
+
+	ks := newKeyStore(zerolog.Nop())
+	_, err := ks.DecrBy("key", math.MinInt64)
+	require.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestIncrByFloat(t *testing.T) {
+	ks := newTestKeyStore()
+
+	value, err := ks.IncrByFloat("float", 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, value)
+
+	// key2 holds an int, it becomes a float64
+	value, err = ks.IncrByFloat("key2", 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 10.5, value)
+
+	require.NoError(t, ks.Set("string", "1.25"))
+	value, err = ks.IncrByFloat("string", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2.25, value)
+	stored, err := ks.Get("string")
+	require.NoError(t, err)
+	assert.Equal(t, "2.25", stored)
+
+	_, err = ks.IncrByFloat("key3", 1)
+	require.ErrorIs(t, err, ErrNotNumeric)
+
+	_, err = ks.IncrByFloat("float", math.Inf(1))
+	require.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestIncrKeepsTTL(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	require.NoError(t, ks.Set("counter", 1, time.Minute))
+
+	_, err := ks.Incr("counter")
+	require.NoError(t, err)
+
+	ttl, err := ks.TTL("counter")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := ks.Incr("counter")
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := ks.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), value)
+}
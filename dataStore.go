@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"reflect"
 	"strings"
 	"time"
@@ -152,6 +153,7 @@ func (ns *Namespace) Create(v map[string]any) error {
 	// Add a field of isSynced to each record inserted
 	normalized["is_synced"] = false
 	ns.dataStore.data[ns.namespace] = append(ns.dataStore.data[ns.namespace], normalized)
+	ns.notify(EventCreate, nil, normalized)
 
 	// Update indexes
 	for key, value := range normalized {
@@ -176,6 +178,15 @@ func (ns *Namespace) Create(v map[string]any) error {
 //	A slice of maps, where each map represents a document that matches the filters.
 //	An error if any occurs during the query process.
 func (ns *Namespace) Query(filters map[string]any) ([]map[string]any, error) {
+	ns.dataStore.stats.counter(ns.namespace).Add(1)
+	defer ns.dataStore.observers.query(ns.namespace, time.Now())
+
+	return ns.query(filters)
+}
+
+// query returns the documents matching the filters without counting a query in the
+// statistics nor telling the observers, for Update() and Delete()
+func (ns *Namespace) query(filters map[string]any) ([]map[string]any, error) {
 	var result []map[string]any
 
 	if len(filters) == 0 {
@@ -310,15 +321,21 @@ func (ns *Namespace) Update(filters map[string]any, newData map[string]any) erro
 	ns.dataStore.mu.Lock()
 	defer ns.dataStore.mu.Unlock()
 
-	matchingDocs, err := ns.Query(filters)
+	matchingDocs, err := ns.query(filters)
 	if err != nil {
 		return err
 	}
 
 	for _, doc := range matchingDocs {
+		var old map[string]any
+		if ns.dataStore.events.enabled() {
+			old = maps.Clone(doc)
+		}
+
 		for key, value := range newData {
 			doc[toSnakeCase(key)] = value
 		}
+		ns.notify(EventUpdate, old, doc)
 	}
 
 	// Rebuild indexes if necessary
@@ -344,7 +361,7 @@ func (ns *Namespace) Delete(filters map[string]any) error {
 	defer ns.dataStore.mu.Unlock()
 
 	// Perform query first to find matching documents
-	matchingDocs, err := ns.Query(filters)
+	matchingDocs, err := ns.query(filters)
 	if err != nil {
 		return err
 	}
@@ -355,6 +372,7 @@ func (ns *Namespace) Delete(filters map[string]any) error {
 			if reflect.DeepEqual(storedDoc, doc) {
 				// Delete the document from the slice
 				ns.dataStore.data[ns.namespace] = append(ns.dataStore.data[ns.namespace][:i], ns.dataStore.data[ns.namespace][i+1:]...)
+				ns.notify(EventDelete, storedDoc, nil)
 				break
 			}
 		}
@@ -417,7 +435,7 @@ func (ns *Namespace) ConnectSQLDB(db *gorm.DB) *ConnectSQLDB {
 }
 
 // Sync periodically synchronizes the in-memory data store with the SQL database.
-// It runs in a separate goroutine and uses a ticker to trigger the synchronization
+// It runs in a separate goroutine and uses a ticker of the cache clock to trigger the synchronization
 // process at the specified interval.
 //
 // Parameters:
@@ -433,48 +451,64 @@ func (ns *Namespace) ConnectSQLDB(db *gorm.DB) *ConnectSQLDB {
 //  7. If an error occurs during the sync, log the error and continue with the next document.
 //  8. Unlock the data store after processing all documents.
 //
-// Note: The synchronization process continues indefinitely until the program terminates.
+// Note: The synchronization process continues until the cache is closed with Close(),
+// which runs a last synchronization before the goroutine exits.
 func (cs *ConnectSQLDB) Sync(interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
+	cs.namespace.dataStore.lifecycle.goroutine(func(done <-chan struct{}) {
+		ticker := cs.namespace.dataStore.clock.NewTicker(interval)
 		defer ticker.Stop()
 
-		for range ticker.C {
-			cs.namespace.dataStore.mu.Lock()
-			for namespace, records := range cs.namespace.dataStore.data {
-				for index, doc := range records {
-					if isSynced, ok := doc["is_synced"].(bool); ok && isSynced {
-						continue
-					}
-
-					docCopy := make(map[string]any)
-					for key, value := range doc {
-						if key != "is_synced" {
-							docCopy[key] = value
-						}
-					}
-
-					if err := cs.DB.Table(namespace).Create(docCopy).Error; err != nil {
-						cs.namespace.dataStore.logger.Err(err).Msgf(
-							"Error syncing document at index %d in namespace %s: %v",
-							index, namespace, err,
-						)
-						// Log the error and continue with the next document
-						continue
-					}
-
-					doc["is_synced"] = true
-					cs.namespace.dataStore.data[namespace][index] = doc
-
-					cs.namespace.dataStore.logger.Info().Msgf(
-						"Synced document at index %d in namespace %s",
-						index, namespace,
-					)
+		for {
+			select {
+			case <-ticker.C():
+				cs.sync()
+			case <-done:
+				// flush the documents created since the last tick
+				cs.sync()
+				return
+			}
+		}
+	})
+}
+
+// sync runs a single synchronization of the unsynced documents with the SQL database
+func (cs *ConnectSQLDB) sync() {
+	cs.namespace.dataStore.mu.Lock()
+	defer cs.namespace.dataStore.mu.Unlock()
+
+	for namespace, records := range cs.namespace.dataStore.data {
+		for index, doc := range records {
+			if isSynced, ok := doc["is_synced"].(bool); ok && isSynced {
+				continue
+			}
+
+			docCopy := make(map[string]any)
+			for key, value := range doc {
+				if key != "is_synced" {
+					docCopy[key] = value
 				}
 			}
-			cs.namespace.dataStore.mu.Unlock()
+
+			if err := cs.DB.Table(namespace).Create(docCopy).Error; err != nil {
+				cs.namespace.dataStore.logger.Err(err).Msgf(
+					"Error syncing document at index %d in namespace %s: %v",
+					index, namespace, err,
+				)
+				cs.namespace.dataStore.observers.sync(SyncSQL, namespace, err)
+				// Log the error and continue with the next document
+				continue
+			}
+
+			doc["is_synced"] = true
+			cs.namespace.dataStore.data[namespace][index] = doc
+			cs.namespace.dataStore.observers.sync(SyncSQL, namespace, nil)
+
+			cs.namespace.dataStore.logger.Info().Msgf(
+				"Synced document at index %d in namespace %s",
+				index, namespace,
+			)
 		}
-	}()
+	}
 }
 
 // ConnectMongoDB initializes a new ConnectMongoDB instance with the provided MongoDB database
@@ -491,7 +525,7 @@ func (ns *Namespace) ConnectMongoDB(db *mongo.Database) *ConnectMongoDB {
 }
 
 // Sync periodically synchronizes the in-memory data store with the MongoDB database.
-// It runs in a separate goroutine and uses a ticker to trigger the synchronization
+// It runs in a separate goroutine and uses a ticker of the cache clock to trigger the synchronization
 // process at the specified interval.
 //
 // The synchronization process involves the following steps:
@@ -503,49 +537,69 @@ func (ns *Namespace) ConnectMongoDB(db *mongo.Database) *ConnectMongoDB {
 // 6. If the insertion is successful, mark the document as synced by setting the "is_synced" field to true.
 // 7. Log the result of the synchronization process.
 //
+// The synchronization process continues until ctx is done or the cache is closed with Close(),
+// which runs a last synchronization before the goroutine exits.
+//
 // Parameters:
 //
 //   - ctx: The context to control the synchronization process.
 //   - interval: The duration between each synchronization attempt.
 func (cm *ConnectMongoDB) Sync(ctx context.Context, interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
+	cm.namespace.dataStore.lifecycle.goroutine(func(done <-chan struct{}) {
+		ticker := cm.namespace.dataStore.clock.NewTicker(interval)
 		defer ticker.Stop()
 
-		for range ticker.C {
-			cm.namespace.dataStore.mu.Lock()
-			for namespace, records := range cm.namespace.dataStore.data {
-				for index, doc := range records {
-					if isSynced, ok := doc["is_synced"].(bool); ok && isSynced {
-						continue
-					}
-
-					docCopy := make(map[string]any)
-					for key, value := range doc {
-						if key != "is_synced" {
-							docCopy[key] = value
-						}
-					}
-
-					if _, err := cm.DB.Collection(namespace).InsertOne(ctx, docCopy); err != nil {
-						cm.namespace.dataStore.logger.Err(err).Msgf(
-							"Error syncing document at index %d in namespace %s: %v",
-							index, namespace, err,
-						)
-						// Log the error and continue with the next document
-						continue
-					}
-
-					doc["is_synced"] = true
-					cm.namespace.dataStore.data[namespace][index] = doc
-
-					cm.namespace.dataStore.logger.Info().Msgf(
-						"Synced document at index %d in namespace %s",
-						index, namespace,
-					)
+		for {
+			select {
+			case <-ticker.C():
+				cm.sync(ctx)
+			case <-ctx.Done():
+				return
+			case <-done:
+				// flush the documents created since the last tick
+				cm.sync(ctx)
+				return
+			}
+		}
+	})
+}
+
+// sync runs a single synchronization of the unsynced documents with the MongoDB database
+func (cm *ConnectMongoDB) sync(ctx context.Context) {
+	cm.namespace.dataStore.mu.Lock()
+	defer cm.namespace.dataStore.mu.Unlock()
+
+	for namespace, records := range cm.namespace.dataStore.data {
+		for index, doc := range records {
+			if isSynced, ok := doc["is_synced"].(bool); ok && isSynced {
+				continue
+			}
+
+			docCopy := make(map[string]any)
+			for key, value := range doc {
+				if key != "is_synced" {
+					docCopy[key] = value
 				}
 			}
-			cm.namespace.dataStore.mu.Unlock()
+
+			if _, err := cm.DB.Collection(namespace).InsertOne(ctx, docCopy); err != nil {
+				cm.namespace.dataStore.logger.Err(err).Msgf(
+					"Error syncing document at index %d in namespace %s: %v",
+					index, namespace, err,
+				)
+				cm.namespace.dataStore.observers.sync(SyncMongoDB, namespace, err)
+				// Log the error and continue with the next document
+				continue
+			}
+
+			doc["is_synced"] = true
+			cm.namespace.dataStore.data[namespace][index] = doc
+			cm.namespace.dataStore.observers.sync(SyncMongoDB, namespace, nil)
+
+			cm.namespace.dataStore.logger.Info().Msgf(
+				"Synced document at index %d in namespace %s",
+				index, namespace,
+			)
 		}
-	}()
+	}
 }
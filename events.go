@@ -0,0 +1,251 @@
+package fscache
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EventSourceKeyStore is the source of the events about KeyStore keys
+	EventSourceKeyStore EventSource = "keystore"
+	// EventSourceDataStore is the source of the events about DataStore documents
+	EventSourceDataStore EventSource = "datastore"
+)
+
+const (
+	// EventSet a key was set
+	EventSet EventOp = "set"
+	// EventOverWrite the value of a key was overwritten
+	EventOverWrite EventOp = "overwrite"
+	// EventDel a key was deleted
+	EventDel EventOp = "del"
+	// EventExpire a key expired and was removed
+	EventExpire EventOp = "expire"
+	// EventTTL the time to live of a key was changed by GetEx(), Expire(), ExpireAt() or
+	// Persist(), its value was not. The resets of a sliding time to live are not reported.
+	EventTTL EventOp = "ttl"
+	// EventCreate a document was created in a namespace
+	EventCreate EventOp = "create"
+	// EventUpdate a document of a namespace was updated
+	EventUpdate EventOp = "update"
+	// EventDelete a document was deleted from a namespace
+	EventDelete EventOp = "delete"
+)
+
+type (
+	// EventSource tells whether an Event is about the KeyStore or the DataStore
+	EventSource string
+
+	// EventOp is the operation an Event reports
+	EventOp string
+
+	// Event reports a change of a KeyStore key or of a DataStore document
+	Event struct {
+		Source EventSource
+		Op     EventOp
+		// Key is the KeyStore key that changed, empty for a DataStore event
+		Key string
+		// Namespace is the namespace of the DataStore document that changed, empty for a KeyStore event
+		Namespace string
+		// OldValue is the value before the change, nil if there was none
+		OldValue any
+		// NewValue is the value after the change, nil if there is none
+		NewValue any
+		// Time is the time of the change
+		Time time.Time
+	}
+
+	// EventFilter selects the events delivered by Watch(), the zero EventFilter selects all of them
+	EventFilter struct {
+		// Source selects the events of a single source, both sources if empty
+		Source EventSource
+		// Ops selects the events of these operations, all operations if empty
+		Ops []EventOp
+		// Pattern selects the events of the keys or namespaces matching a Redis glob-style
+		// pattern, all keys and namespaces if empty
+		Pattern string
+	}
+
+	// notifier delivers the events to the watchers. A nil notifier drops the events.
+	notifier struct {
+		mu       sync.RWMutex
+		watchers map[*watcher]struct{}
+		// count is the number of watchers, it saves building events nobody watches
+		count      atomic.Int64
+		bufferSize int
+		policy     SlowConsumerPolicy
+	}
+
+	// watcher is a Watch() subscription, events are delivered on ch
+	watcher struct {
+		filter EventFilter
+		ch     chan Event
+		done   chan struct{}
+		once   sync.Once
+	}
+)
+
+// newNotifier returns a notifier buffering bufferSize events per watcher
+func newNotifier(bufferSize int, policy SlowConsumerPolicy) *notifier {
+	return &notifier{
+		watchers:   make(map[*watcher]struct{}),
+		bufferSize: bufferSize,
+		policy:     policy,
+	}
+}
+
+// Watch() returns the events selected by filter: keys set, overwritten, deleted, expired or
+// given another time to live in the KeyStore, documents created, updated or deleted through
+// a DataStore Namespace.
+// The watch lasts until ctx is done or the cache is closed, the event channel is then closed.
+//
+// Events are sent while the change is applied, so they are never blocking: the events
+// arriving while the buffer of the watcher is full are dropped, or the watcher is
+// disconnected with SlowConsumerDisconnect, see WithPubSub().
+func (c *Cache) Watch(ctx context.Context, filter EventFilter) <-chan Event {
+	return c.events.watch(ctx, c.lifecycle, filter)
+}
+
+// watch registers a watcher and removes it once ctx is done or the lifecycle stops
+func (n *notifier) watch(ctx context.Context, lc *lifecycle, filter EventFilter) <-chan Event {
+	w := &watcher{
+		filter: filter,
+		ch:     make(chan Event, n.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	n.mu.Lock()
+	n.watchers[w] = struct{}{}
+	n.count.Add(1)
+	n.mu.Unlock()
+
+	started := lc.goroutine(func(done <-chan struct{}) {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		case <-w.done:
+		}
+		n.unwatch(w)
+	})
+	if !started {
+		n.unwatch(w)
+	}
+
+	return w.ch
+}
+
+// unwatch removes the watcher and closes its event channel, it is safe to call more than once
+func (n *notifier) unwatch(w *watcher) {
+	w.once.Do(func() {
+		n.mu.Lock()
+		delete(n.watchers, w)
+		n.count.Add(-1)
+		close(w.done)
+		close(w.ch)
+		n.mu.Unlock()
+	})
+}
+
+// enabled reports whether anybody watches the events
+func (n *notifier) enabled() bool {
+	return n != nil && n.count.Load() > 0
+}
+
+// emit delivers the event to the watchers whose filter selects it. It never blocks,
+// so it can be called while holding the locks of the storage.
+func (n *notifier) emit(event Event) {
+	if !n.enabled() {
+		return
+	}
+
+	var disconnect []*watcher
+
+	n.mu.RLock()
+	for w := range n.watchers {
+		if !w.filter.selects(event) {
+			continue
+		}
+
+		select {
+		case w.ch <- event:
+		default:
+			if n.policy == SlowConsumerDisconnect {
+				disconnect = append(disconnect, w)
+			}
+		}
+	}
+	n.mu.RUnlock()
+
+	for _, w := range disconnect {
+		n.unwatch(w)
+	}
+}
+
+// selects reports whether the filter selects the event
+func (f EventFilter) selects(event Event) bool {
+	if f.Source != "" && f.Source != event.Source {
+		return false
+	}
+
+	if len(f.Ops) > 0 && !slices.Contains(f.Ops, event.Op) {
+		return false
+	}
+
+	if f.Pattern != "" {
+		name := event.Key
+		if event.Source == EventSourceDataStore {
+			name = event.Namespace
+		}
+		if !matchPattern(f.Pattern, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// notify counts a change of a key in the statistics and emits a KeyStore event. The values
+// are exposed like the read paths do, so it must be called while holding the lock of the shard.
+func (ks *KeyStore) notify(op EventOp, key string, oldValue, newValue any) {
+	ks.stats.record(op)
+	if !ks.events.enabled() {
+		return
+	}
+
+	ks.events.emit(Event{
+		Source:   EventSourceKeyStore,
+		Op:       op,
+		Key:      key,
+		OldValue: exposed(oldValue),
+		NewValue: exposed(newValue),
+		Time:     ks.clock.Now(),
+	})
+}
+
+// notify emits a DataStore event about a document of the namespace. The documents are
+// copied since they are updated in place, it must be called while holding the DataStore lock.
+func (ns *Namespace) notify(op EventOp, oldDoc, newDoc map[string]any) {
+	ds := ns.dataStore
+	if !ds.events.enabled() {
+		return
+	}
+
+	event := Event{
+		Source:    EventSourceDataStore,
+		Op:        op,
+		Namespace: ns.namespace,
+		Time:      ds.clock.Now(),
+	}
+	if oldDoc != nil {
+		event.OldValue = maps.Clone(oldDoc)
+	}
+	if newDoc != nil {
+		event.NewValue = maps.Clone(newDoc)
+	}
+
+	ds.events.emit(event)
+}
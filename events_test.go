@@ -0,0 +1,312 @@
+package fscache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receive returns the next event, failing the test if none arrives
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestWatchKeyStore(t *testing.T) {
+	clock := newManualClock(time.Now())
+	fs := New(WithClock(clock), WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	events := fs.Watch(context.Background(), EventFilter{Source: EventSourceKeyStore})
+	ks := fs.KeyStore()
+
+	require.NoError(t, ks.Set("key1", "value1"))
+	event := receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+	assert.Equal(t, "key1", event.Key)
+	assert.Nil(t, event.OldValue)
+	assert.Equal(t, "value1", event.NewValue)
+	assert.False(t, event.Time.IsZero())
+
+	require.NoError(t, ks.OverWrite("key1", "value2"))
+	event = receive(t, events)
+	assert.Equal(t, EventOverWrite, event.Op)
+	assert.Equal(t, "value1", event.OldValue)
+	assert.Equal(t, "value2", event.NewValue)
+
+	require.NoError(t, ks.OverWriteWithKey("key1", "key2", "value3"))
+	event = receive(t, events)
+	assert.Equal(t, Event{Source: EventSourceKeyStore, Op: EventDel, Key: "key1", OldValue: "value2", Time: event.Time}, event)
+	event = receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+	assert.Equal(t, "key2", event.Key)
+
+	require.NoError(t, ks.Del("key2"))
+	event = receive(t, events)
+	assert.Equal(t, EventDel, event.Op)
+	assert.Equal(t, "value3", event.OldValue)
+
+	// keys reclaimed by the active expiry cycle
+	require.NoError(t, ks.Set("key3", "value", time.Minute))
+	receive(t, events)
+	ks.activeExpireCycle(time.Now().Add(time.Hour))
+	event = receive(t, events)
+	assert.Equal(t, EventExpire, event.Op)
+	assert.Equal(t, "key3", event.Key)
+	assert.Equal(t, "value", event.OldValue)
+
+	// and lazily on access
+	require.NoError(t, ks.Set("key4", "value", time.Minute))
+	receive(t, events)
+	clock.Advance(time.Minute)
+	_, err := ks.Get("key4")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	event = receive(t, events)
+	assert.Equal(t, EventExpire, event.Op)
+	assert.Equal(t, "key4", event.Key)
+}
+
+func TestWatchTTL(t *testing.T) {
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	events := fs.Watch(context.Background(), EventFilter{Source: EventSourceKeyStore})
+	ks := fs.KeyStore()
+
+	require.NoError(t, ks.Set("key", "value"))
+	receive(t, events)
+
+	require.NoError(t, ks.Expire("key", time.Minute))
+	event := receive(t, events)
+	assert.Equal(t, Event{Source: EventSourceKeyStore, Op: EventTTL, Key: "key", OldValue: "value", NewValue: "value", Time: event.Time}, event)
+
+	_, err := ks.GetEx("key", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, EventTTL, receive(t, events).Op)
+
+	require.NoError(t, ks.Persist("key"))
+	assert.Equal(t, EventTTL, receive(t, events).Op)
+
+	// SetMany() overwrites like OverWrite() does
+	_, err = ks.SetMany([]map[string]KeyStoreData{{"key": {Value: "updated"}, "other": {Value: 1}}})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		event = receive(t, events)
+		switch event.Key {
+		case "key":
+			assert.Equal(t, EventOverWrite, event.Op)
+			assert.Equal(t, "value", event.OldValue)
+			assert.Equal(t, "updated", event.NewValue)
+		case "other":
+			assert.Equal(t, EventSet, event.Op)
+			assert.Nil(t, event.OldValue)
+		}
+	}
+}
+
+func TestWatchCounters(t *testing.T) {
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	events := fs.Watch(context.Background(), EventFilter{Source: EventSourceKeyStore})
+	ks := fs.KeyStore()
+
+	_, err := ks.IncrBy("hits", 2)
+	require.NoError(t, err)
+	event := receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+	assert.Equal(t, "hits", event.Key)
+	assert.Nil(t, event.OldValue)
+	assert.Equal(t, int64(2), event.NewValue)
+
+	_, err = ks.Incr("hits")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventOverWrite, event.Op)
+	assert.Equal(t, int64(2), event.OldValue)
+	assert.Equal(t, int64(3), event.NewValue)
+
+	_, err = ks.IncrByFloat("ratio", 0.5)
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+	assert.Equal(t, 0.5, event.NewValue)
+
+	_, err = ks.IncrByFloat("ratio", 0.25)
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventOverWrite, event.Op)
+	assert.Equal(t, 0.5, event.OldValue)
+	assert.Equal(t, 0.75, event.NewValue)
+}
+
+func TestWatchCollections(t *testing.T) {
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	events := fs.Watch(context.Background(), EventFilter{Source: EventSourceKeyStore})
+	ks := fs.KeyStore()
+
+	// lists
+	_, err := ks.RPush("list", "a")
+	require.NoError(t, err)
+	event := receive(t, events)
+	assert.Equal(t, Event{Source: EventSourceKeyStore, Op: EventSet, Key: "list", NewValue: []any{"a"}, Time: event.Time}, event)
+
+	_, err = ks.RPush("list", "b")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventOverWrite, event.Op)
+	assert.Equal(t, []any{"a"}, event.OldValue)
+	assert.Equal(t, []any{"a", "b"}, event.NewValue)
+
+	_, err = ks.LPop("list")
+	require.NoError(t, err)
+	receive(t, events)
+	_, err = ks.LPop("list")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, Event{Source: EventSourceKeyStore, Op: EventDel, Key: "list", OldValue: []any{"b"}, Time: event.Time}, event)
+
+	// hashes
+	_, err = ks.HSet("hash", map[string]any{"a": 1})
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+	assert.Equal(t, map[string]any{"a": 1}, event.NewValue)
+
+	_, err = ks.HDel("hash", "a")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventDel, event.Op)
+	assert.Equal(t, map[string]any{"a": 1}, event.OldValue)
+
+	// sets
+	_, err = ks.SAdd("set", "a")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+	assert.Equal(t, []string{"a"}, event.NewValue)
+
+	_, err = ks.SRem("set", "a")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventDel, event.Op)
+	assert.Equal(t, []string{"a"}, event.OldValue)
+
+	// sorted sets
+	_, err = ks.ZAdd("zset", ZMember{Member: "a", Score: 1})
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventSet, event.Op)
+
+	_, err = ks.ZIncrBy("zset", "a", 2)
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventOverWrite, event.Op)
+	assert.Equal(t, []ZMember{{Member: "a", Score: 1}}, event.OldValue)
+	assert.Equal(t, []ZMember{{Member: "a", Score: 3}}, event.NewValue)
+
+	_, err = ks.ZRem("zset", "a")
+	require.NoError(t, err)
+	event = receive(t, events)
+	assert.Equal(t, EventDel, event.Op)
+	assert.Equal(t, []ZMember{{Member: "a", Score: 3}}, event.OldValue)
+}
+
+func TestWatchDataStore(t *testing.T) {
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	events := fs.Watch(context.Background(), EventFilter{Pattern: "user*"})
+	ns := fs.DataStore().Namespace("user")
+
+	require.NoError(t, ns.Create(map[string]any{"name": "Jane"}))
+	event := receive(t, events)
+	assert.Equal(t, EventSourceDataStore, event.Source)
+	assert.Equal(t, EventCreate, event.Op)
+	assert.Equal(t, "users", event.Namespace)
+	assert.Equal(t, map[string]any{"name": "Jane", "is_synced": false}, event.NewValue)
+
+	require.NoError(t, ns.Update(map[string]any{"name": "Jane"}, map[string]any{"name": "Jane Doe"}))
+	event = receive(t, events)
+	assert.Equal(t, EventUpdate, event.Op)
+	assert.Equal(t, map[string]any{"name": "Jane", "is_synced": false}, event.OldValue)
+	assert.Equal(t, map[string]any{"name": "Jane Doe", "is_synced": false}, event.NewValue)
+
+	require.NoError(t, ns.Delete(map[string]any{"name": "Jane Doe"}))
+	event = receive(t, events)
+	assert.Equal(t, EventDelete, event.Op)
+	assert.Equal(t, map[string]any{"name": "Jane Doe", "is_synced": false}, event.OldValue)
+	assert.Nil(t, event.NewValue)
+
+	// other namespaces are filtered out
+	orders := fs.DataStore().Namespace("order")
+	require.NoError(t, orders.Create(map[string]any{"id": 1}))
+	require.NoError(t, fs.KeyStore().Set("key1", "value1"))
+	assert.Empty(t, events)
+}
+
+func TestEventFilter(t *testing.T) {
+	set := Event{Source: EventSourceKeyStore, Op: EventSet, Key: "user:1"}
+	create := Event{Source: EventSourceDataStore, Op: EventCreate, Namespace: "users"}
+
+	testCases := map[string]struct {
+		filter EventFilter
+		set    bool
+		create bool
+	}{
+		"all":      {filter: EventFilter{}, set: true, create: true},
+		"source":   {filter: EventFilter{Source: EventSourceDataStore}, create: true},
+		"ops":      {filter: EventFilter{Ops: []EventOp{EventSet, EventDel}}, set: true},
+		"pattern":  {filter: EventFilter{Pattern: "user:*"}, set: true},
+		"key+ns":   {filter: EventFilter{Pattern: "user*"}, set: true, create: true},
+		"no match": {filter: EventFilter{Source: EventSourceKeyStore, Ops: []EventOp{EventExpire}}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.set, tc.filter.selects(set))
+			assert.Equal(t, tc.create, tc.filter.selects(create))
+		})
+	}
+}
+
+func TestWatchEnds(t *testing.T) {
+	fs := New(
+		WithPersistPath(filepath.Join(t.TempDir(), "storage.json")),
+		WithPubSub(1, SlowConsumerDisconnect),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := fs.Watch(ctx, EventFilter{})
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.False(t, fs.(*Cache).events.enabled())
+
+	// a slow watcher is disconnected
+	events = fs.Watch(context.Background(), EventFilter{})
+	require.NoError(t, fs.KeyStore().Set("key1", 1))
+	require.NoError(t, fs.KeyStore().Set("key2", 2))
+	assert.Equal(t, "key1", receive(t, events).Key)
+	_, ok = <-events
+	assert.False(t, ok)
+
+	// closing the cache ends the watches
+	events = fs.Watch(context.Background(), EventFilter{})
+	require.NoError(t, fs.Close(context.Background()))
+	_, ok = <-events
+	assert.False(t, ok)
+}
@@ -0,0 +1,420 @@
+package fscache
+
+import (
+	"container/list"
+	"sync"
+)
+
+type (
+	// EvictionPolicy decides which key leaves the KeyStore once it grows over its capacity.
+	// Implementations must be safe for concurrent use, the KeyStore calls them from many goroutines.
+	EvictionPolicy interface {
+		// Add records a key newly stored in the KeyStore
+		Add(key string)
+		// Access records a read or an update of a key already stored in the KeyStore
+		Access(key string)
+		// Remove forgets a key deleted from the KeyStore
+		Remove(key string)
+		// Victim picks the next key to evict and stops tracking it.
+		// It returns false when the policy is not tracking any key.
+		Victim() (string, bool)
+	}
+
+	// lruPolicy evicts the least recently used key
+	lruPolicy struct {
+		mu    sync.Mutex
+		order *list.List
+		items map[string]*list.Element
+	}
+
+	// fifoPolicy evicts the oldest stored key regardless of how often it is used
+	fifoPolicy struct {
+		mu    sync.Mutex
+		order *list.List
+		items map[string]*list.Element
+	}
+
+	// lfuPolicy evicts the least frequently used key, ties are broken by recency.
+	// The keys are grouped in buckets by frequency and the buckets are kept in a list
+	// ordered by frequency, so the least frequently used keys are always in the first one.
+	lfuPolicy struct {
+		mu      sync.Mutex
+		items   map[string]*list.Element
+		buckets *list.List
+	}
+
+	// lfuBucket holds the keys of the lfuPolicy accessed freq times, most recent first
+	lfuBucket struct {
+		freq    int
+		entries *list.List
+	}
+
+	// lfuEntry is a key tracked by the lfuPolicy along with the bucket of its frequency
+	lfuEntry struct {
+		key    string
+		bucket *list.Element
+	}
+
+	// arcPolicy implements the Adaptive Replacement Cache algorithm. It balances
+	// recency (t1) and frequency (t2) and remembers recently evicted keys in the
+	// ghost lists b1 and b2 to adapt the target size p of t1.
+	arcPolicy struct {
+		mu             sync.Mutex
+		size           int
+		p              int
+		t1, t2, b1, b2 *arcList
+	}
+
+	// arcList is an ordered set of keys used by the arcPolicy, the front is the most recent
+	arcList struct {
+		order *list.List
+		items map[string]*list.Element
+	}
+)
+
+// NewLRUPolicy returns an EvictionPolicy that evicts the least recently used key
+func NewLRUPolicy() EvictionPolicy {
+	return &lruPolicy{
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Add records a key newly stored in the KeyStore
+func (p *lruPolicy) Add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.order.MoveToFront(elem)
+		return
+	}
+
+	p.items[key] = p.order.PushFront(key)
+}
+
+// Access marks a key as the most recently used
+func (p *lruPolicy) Access(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.order.MoveToFront(elem)
+	}
+}
+
+// Remove forgets a key deleted from the KeyStore
+func (p *lruPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.order.Remove(elem)
+		delete(p.items, key)
+	}
+}
+
+// Victim returns the least recently used key
+func (p *lruPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elem := p.order.Back()
+	if elem == nil {
+		return "", false
+	}
+
+	key := p.order.Remove(elem).(string)
+	delete(p.items, key)
+
+	return key, true
+}
+
+// NewFIFOPolicy returns an EvictionPolicy that evicts keys in the order they were stored
+func NewFIFOPolicy() EvictionPolicy {
+	return &fifoPolicy{
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Add records a key newly stored in the KeyStore
+func (p *fifoPolicy) Add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.items[key]; ok {
+		return
+	}
+
+	p.items[key] = p.order.PushFront(key)
+}
+
+// Access is a no-op, accesses don't change the eviction order of a FIFO
+func (p *fifoPolicy) Access(string) {}
+
+// Remove forgets a key deleted from the KeyStore
+func (p *fifoPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.order.Remove(elem)
+		delete(p.items, key)
+	}
+}
+
+// Victim returns the oldest stored key
+func (p *fifoPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elem := p.order.Back()
+	if elem == nil {
+		return "", false
+	}
+
+	key := p.order.Remove(elem).(string)
+	delete(p.items, key)
+
+	return key, true
+}
+
+// NewLFUPolicy returns an EvictionPolicy that evicts the least frequently used key.
+// Every operation runs in constant time.
+func NewLFUPolicy() EvictionPolicy {
+	return &lfuPolicy{
+		items:   make(map[string]*list.Element),
+		buckets: list.New(),
+	}
+}
+
+// Add records a key newly stored in the KeyStore
+func (p *lfuPolicy) Add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.increment(elem)
+		return
+	}
+
+	first := p.buckets.Front()
+	if first == nil || first.Value.(*lfuBucket).freq != 1 {
+		first = p.buckets.PushFront(&lfuBucket{freq: 1, entries: list.New()})
+	}
+
+	p.items[key] = first.Value.(*lfuBucket).entries.PushFront(&lfuEntry{key: key, bucket: first})
+}
+
+// Access increments the frequency of a key
+func (p *lfuPolicy) Access(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.increment(elem)
+	}
+}
+
+// Remove forgets a key deleted from the KeyStore
+func (p *lfuPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if elem, ok := p.items[key]; ok {
+		p.unlink(elem)
+		delete(p.items, key)
+	}
+}
+
+// Victim returns the least frequently used key
+func (p *lfuPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	first := p.buckets.Front()
+	if first == nil {
+		return "", false
+	}
+
+	elem := first.Value.(*lfuBucket).entries.Back()
+	entry := elem.Value.(*lfuEntry)
+	p.unlink(elem)
+	delete(p.items, entry.key)
+
+	return entry.key, true
+}
+
+// unlink removes an element from its frequency bucket, dropping the bucket once empty
+func (p *lfuPolicy) unlink(elem *list.Element) {
+	entry := elem.Value.(*lfuEntry)
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.entries.Remove(elem)
+
+	if bucket.entries.Len() == 0 {
+		p.buckets.Remove(entry.bucket)
+	}
+}
+
+// increment moves a key into the bucket of the next frequency, creating it if needed
+func (p *lfuPolicy) increment(elem *list.Element) {
+	entry := elem.Value.(*lfuEntry)
+	current := entry.bucket
+	freq := current.Value.(*lfuBucket).freq + 1
+
+	next := current.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != freq {
+		next = p.buckets.InsertAfter(&lfuBucket{freq: freq, entries: list.New()}, current)
+	}
+
+	p.unlink(elem)
+	entry.bucket = next
+	p.items[entry.key] = next.Value.(*lfuBucket).entries.PushFront(entry)
+}
+
+// NewARCPolicy returns an EvictionPolicy implementing the Adaptive Replacement Cache
+// algorithm. size is the number of keys the cache is expected to hold, it should
+// match the max entries of the KeyStore and bounds how many evicted keys are remembered.
+func NewARCPolicy(size int) EvictionPolicy {
+	if size < 1 {
+		size = 1
+	}
+
+	return &arcPolicy{
+		size: size,
+		t1:   newARCList(),
+		t2:   newARCList(),
+		b1:   newARCList(),
+		b2:   newARCList(),
+	}
+}
+
+// Add records a key newly stored in the KeyStore. A key found in a ghost list
+// adapts the target size of t1 and goes straight to the frequency list.
+func (p *arcPolicy) Add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case p.t1.has(key) || p.t2.has(key):
+		p.t1.remove(key)
+		p.t2.pushFront(key)
+	case p.b1.has(key):
+		delta := 1
+		if p.b1.len() < p.b2.len() {
+			delta = p.b2.len() / p.b1.len()
+		}
+		p.p = min(p.p+delta, p.size)
+		p.b1.remove(key)
+		p.t2.pushFront(key)
+	case p.b2.has(key):
+		delta := 1
+		if p.b2.len() < p.b1.len() {
+			delta = p.b1.len() / p.b2.len()
+		}
+		p.p = max(p.p-delta, 0)
+		p.b2.remove(key)
+		p.t2.pushFront(key)
+	default:
+		p.t1.pushFront(key)
+	}
+}
+
+// Access promotes a key to the front of the frequency list
+func (p *arcPolicy) Access(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.t1.remove(key) || p.t2.has(key) {
+		p.t2.pushFront(key)
+	}
+}
+
+// Remove forgets a key deleted from the KeyStore
+func (p *arcPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.t1.remove(key)
+	p.t2.remove(key)
+}
+
+// Victim evicts from t1 while it is over its target size, otherwise from t2,
+// and remembers the evicted key in the matching ghost list
+func (p *arcPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var key string
+	switch {
+	case p.t1.len() > 0 && (p.t1.len() > p.p || p.t2.len() == 0):
+		key = p.t1.popBack()
+		p.b1.pushFront(key)
+	case p.t2.len() > 0:
+		key = p.t2.popBack()
+		p.b2.pushFront(key)
+	default:
+		return "", false
+	}
+
+	for p.b1.len()+p.b2.len() > p.size {
+		if p.b1.len() > p.b2.len() {
+			p.b1.popBack()
+		} else {
+			p.b2.popBack()
+		}
+	}
+
+	return key, true
+}
+
+// newARCList returns an empty arcList
+func newARCList() *arcList {
+	return &arcList{
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// has reports whether the key is in the list
+func (l *arcList) has(key string) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+// len returns the number of keys in the list
+func (l *arcList) len() int {
+	return len(l.items)
+}
+
+// pushFront inserts a key at the front of the list, moving it there if present
+func (l *arcList) pushFront(key string) {
+	if elem, ok := l.items[key]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(key)
+}
+
+// remove deletes a key from the list and reports whether it was present
+func (l *arcList) remove(key string) bool {
+	elem, ok := l.items[key]
+	if !ok {
+		return false
+	}
+
+	l.order.Remove(elem)
+	delete(l.items, key)
+
+	return true
+}
+
+// popBack removes and returns the least recent key of the list
+func (l *arcList) popBack() string {
+	key := l.order.Remove(l.order.Back()).(string)
+	delete(l.items, key)
+
+	return key
+}
@@ -0,0 +1,196 @@
+package fscache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// victims drains a policy and returns the keys in the order they would be evicted
+func victims(policy EvictionPolicy) []string {
+	var keys []string
+	for {
+		key, ok := policy.Victim()
+		if !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func TestLRUPolicy(t *testing.T) {
+	policy := NewLRUPolicy()
+	policy.Add("key1")
+	policy.Add("key2")
+	policy.Add("key3")
+	policy.Access("key1")
+	policy.Remove("key2")
+
+	assert.Equal(t, []string{"key3", "key1"}, victims(policy))
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	policy := NewFIFOPolicy()
+	policy.Add("key1")
+	policy.Add("key2")
+	policy.Add("key3")
+	policy.Access("key1")
+	policy.Remove("key2")
+
+	assert.Equal(t, []string{"key1", "key3"}, victims(policy))
+}
+
+func TestLFUPolicy(t *testing.T) {
+	policy := NewLFUPolicy()
+	policy.Add("key1")
+	policy.Add("key2")
+	policy.Add("key3")
+	policy.Add("key4")
+	policy.Access("key1")
+	policy.Access("key1")
+	policy.Access("key2")
+	policy.Access("key4")
+	policy.Remove("key4")
+
+	assert.Equal(t, []string{"key3", "key2", "key1"}, victims(policy))
+}
+
+func TestLFUPolicyFrequencyGaps(t *testing.T) {
+	policy := NewLFUPolicy()
+	policy.Add("key1")
+	policy.Add("key2")
+	policy.Add("key3")
+	for i := 0; i < 2; i++ {
+		policy.Access("key2")
+	}
+	for i := 0; i < 4; i++ {
+		policy.Access("key3")
+	}
+
+	// the least frequent bucket empties, the next one holds the victim
+	policy.Remove("key1")
+	key, ok := policy.Victim()
+	require.True(t, ok)
+	assert.Equal(t, "key2", key)
+
+	// a new key starts over with the lowest frequency
+	policy.Add("key4")
+	assert.Equal(t, []string{"key4", "key3"}, victims(policy))
+
+	_, ok = policy.Victim()
+	assert.False(t, ok)
+}
+
+func TestARCPolicy(t *testing.T) {
+	policy := NewARCPolicy(2)
+	policy.Add("key1")
+	policy.Add("key2")
+	policy.Access("key1") // key1 moves to the frequency list
+
+	key, ok := policy.Victim()
+	require.True(t, ok)
+	assert.Equal(t, "key2", key)
+
+	// key2 comes back from the ghost list, it is now considered frequent and
+	// the recency list grows its target size, so key3 is protected from eviction
+	policy.Add("key2")
+	policy.Add("key3")
+
+	assert.Equal(t, []string{"key1", "key2", "key3"}, victims(policy))
+}
+
+func TestSetCapacityEntries(t *testing.T) {
+	testCases := map[string]struct {
+		policy   EvictionPolicy
+		expected []string
+	}{
+		"lru": {
+			policy:   NewLRUPolicy(),
+			expected: []string{"key1", "key4", "key5"},
+		},
+		"fifo": {
+			policy:   NewFIFOPolicy(),
+			expected: []string{"key3", "key4", "key5"},
+		},
+		"lfu": {
+			policy:   NewLFUPolicy(),
+			expected: []string{"key1", "key4", "key5"},
+		},
+		"arc": {
+			policy:   NewARCPolicy(3),
+			expected: []string{"key1", "key4", "key5"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ks := newKeyStore(zerolog.Nop())
+			ks.SetEvictionPolicy(testCase.policy)
+			ks.SetCapacity(3, 0)
+
+			for i := 1; i <= 3; i++ {
+				require.NoError(t, ks.Set("key"+strconv.Itoa(i), i, time.Minute))
+			}
+
+			_, err := ks.Get("key1")
+			require.NoError(t, err)
+
+			require.NoError(t, ks.Set("key4", 4, time.Minute))
+			require.NoError(t, ks.Set("key5", 5, time.Minute))
+
+			assert.Equal(t, 3, ks.Size())
+			assert.ElementsMatch(t, testCase.expected, ks.Keys())
+		})
+	}
+}
+
+func TestSetCapacityBytes(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	value := strings.Repeat("a", 1024)
+	ks.SetCapacity(0, 3*ks.estimateSize("key1", value))
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, ks.Set("key"+strconv.Itoa(i), value, time.Minute))
+	}
+
+	assert.Equal(t, 3, ks.Size())
+	assert.ElementsMatch(t, []string{"key3", "key4", "key5"}, ks.Keys())
+
+	require.NoError(t, ks.Del("key5"))
+	assert.Equal(t, 2*ks.estimateSize("key1", value), ks.usage.bytes.Load())
+}
+
+func TestSetCapacityShrink(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, ks.Set("key"+strconv.Itoa(i), i, time.Minute))
+	}
+
+	ks.SetCapacity(4, 0)
+	assert.Equal(t, 4, ks.Size())
+}
+
+func TestSetEvictionPolicyConcurrentWrites(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+
+	// the keys written while the policy is swapped are all tracked by the new policy
+	const keys = 20000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < keys; i++ {
+			_ = ks.Set("key"+strconv.Itoa(i), i)
+		}
+	}()
+	for ks.Size() < keys/2 {
+		ks.SetEvictionPolicy(NewFIFOPolicy())
+	}
+	<-done
+
+	assert.Len(t, victims(ks.policy()), keys)
+}
@@ -0,0 +1,125 @@
+package fscache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultActiveExpiryInterval is how often the active expiry cycle runs by default
+	defaultActiveExpiryInterval = 100 * time.Millisecond
+	// defaultActiveExpirySamples is the number of keys sampled per shard by default
+	defaultActiveExpirySamples = 20
+	// activeExpiryMaxRounds bounds how many times a shard is sampled in a single cycle
+	activeExpiryMaxRounds = 16
+)
+
+// keyStoreExpiry holds the settings of the active expiry cycle
+type keyStoreExpiry struct {
+	interval atomic.Int64
+	samples  atomic.Int64
+}
+
+// expired reports whether the data object is expired at the given time.
+// A zero duration never expires.
+func (d KeyStoreData) expired(now time.Time) bool {
+	return !d.Duration.IsZero() && !now.Before(d.Duration)
+}
+
+// expiresAt returns the duration of a data object stored at now with an optional ttl.
+// A missing, zero or negative ttl yields a zero duration, which never expires.
+func expiresAt(now time.Time, ttl []time.Duration) time.Time {
+	if len(ttl) == 0 || ttl[0] <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(ttl[0])
+}
+
+// SetActiveExpiry() sets the rate of the active expiry cycle. Every interval, up to
+// samples keys having a duration are picked at random from each shard and the expired
+// ones are removed. A shard is sampled again while more than a quarter of its sample
+// was expired. Non-positive values keep the current setting.
+func (ks *KeyStore) SetActiveExpiry(interval time.Duration, samples int) {
+	if interval > 0 {
+		ks.expiry.interval.Store(int64(interval))
+	}
+
+	if samples > 0 {
+		ks.expiry.samples.Store(int64(samples))
+	}
+}
+
+// activeExpiryInterval returns how often the active expiry cycle runs
+func (ks *KeyStore) activeExpiryInterval() time.Duration {
+	return time.Duration(ks.expiry.interval.Load())
+}
+
+// live returns the data object stored under key if it is not expired. An expired
+// data object is removed on the spot. The caller must hold the write lock of the shard.
+func (ks *KeyStore) live(shard *keyStoreShard, key string, now time.Time) (KeyStoreData, bool) {
+	data, ok := shard.items[key]
+	if !ok {
+		return data, false
+	}
+
+	if data.expired(now) {
+		ks.remove(shard, key)
+		ks.notify(EventExpire, key, data.Value, nil)
+		ks.evicted(key, data.Value, EvictExpired)
+		ks.logger.Info().Msgf("data object [%v] got expired", key)
+		return KeyStoreData{}, false
+	}
+
+	return data, true
+}
+
+// expire removes the key if it is expired. It is used by the read paths which
+// only hold a read lock when they come across an expired data object.
+func (ks *KeyStore) expire(key string) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	ks.live(shard, key, ks.clock.Now())
+}
+
+// activeExpireCycle samples keys having a duration from every shard and removes the
+// expired ones, locking a single shard at a time. Like Redis, a shard is sampled again
+// while more than a quarter of its sample was expired, so the memory held by expired keys
+// gets reclaimed without a full scan. It returns the number of keys removed.
+func (ks *KeyStore) activeExpireCycle(now time.Time) int {
+	samples := int(ks.expiry.samples.Load())
+
+	var removed int
+	for _, shard := range ks.shards {
+		for round := 0; round < activeExpiryMaxRounds; round++ {
+			var sampled, expired int
+
+			shard.mu.Lock()
+			// map iteration starts at a random position, which makes this a random sample
+			for key := range shard.expires {
+				if sampled == samples {
+					break
+				}
+				sampled++
+
+				if data := shard.items[key]; data.expired(now) {
+					ks.remove(shard, key)
+					ks.notify(EventExpire, key, data.Value, nil)
+					ks.evicted(key, data.Value, EvictExpired)
+					ks.logger.Info().Msgf("data object [%v] got expired", key)
+					expired++
+				}
+			}
+			shard.mu.Unlock()
+
+			removed += expired
+			if expired*4 <= sampled || sampled < samples {
+				break
+			}
+		}
+	}
+
+	return removed
+}
@@ -0,0 +1,117 @@
+package fscache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExpiredKeyStore returns a KeyStore holding an expired key and a live key
+func newExpiredKeyStore(t *testing.T) KeyStore {
+	ks := newKeyStore(zerolog.Nop())
+	_, err := ks.SetMany([]map[string]KeyStoreData{
+		{
+			"expired": KeyStoreData{
+				Value:    "stale",
+				Duration: time.Now().Add(-time.Second),
+			},
+			"live": KeyStoreData{
+				Value:    "fresh",
+				Duration: time.Now().Add(time.Minute),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return ks
+}
+
+func TestLazyExpiry(t *testing.T) {
+	ks := newExpiredKeyStore(t)
+	assert.Equal(t, 2, ks.Size()) // not reclaimed yet
+
+	assert.Equal(t, []string{"live"}, ks.Keys())
+	assert.Equal(t, []any{"fresh"}, ks.Values())
+	assert.Equal(t, []map[string]any{{"live": "fresh"}}, ks.KeyValuePairs())
+
+	_, err := ks.TypeOf("expired")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = ks.Get("expired")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, 1, ks.Size()) // reclaimed by Get()
+
+	ks = newExpiredKeyStore(t)
+	assert.Equal(t, []map[string]any{{"live": "fresh"}}, ks.GetMany([]string{"expired", "live"}))
+	assert.Equal(t, 1, ks.Size()) // reclaimed by GetMany()
+}
+
+func TestLazyExpiryWrites(t *testing.T) {
+	ks := newExpiredKeyStore(t)
+	require.ErrorIs(t, ks.OverWrite("expired", "value"), ErrKeyNotFound)
+
+	ks = newExpiredKeyStore(t)
+	require.ErrorIs(t, ks.Del("expired"), ErrKeyNotFound)
+
+	ks = newExpiredKeyStore(t)
+	require.ErrorIs(t, ks.OverWriteWithKey("expired", "newKey", "value"), ErrKeyNotFound)
+
+	ks = newExpiredKeyStore(t)
+	require.NoError(t, ks.Set("expired", "value"))
+	value, err := ks.Get("expired")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestSetWithoutDuration(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	require.NoError(t, ks.Set("key", "value"))
+
+	value, err := ks.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestActiveExpireCycle(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+
+	data := map[string]KeyStoreData{}
+	for i := 0; i < 1000; i++ {
+		data["expired"+strconv.Itoa(i)] = KeyStoreData{
+			Value:    i,
+			Duration: time.Now().Add(-time.Second),
+		}
+	}
+	for i := 0; i < 100; i++ {
+		data["live"+strconv.Itoa(i)] = KeyStoreData{
+			Value:    i,
+			Duration: time.Now().Add(time.Minute),
+		}
+		data["persistent"+strconv.Itoa(i)] = KeyStoreData{
+			Value: i,
+		}
+	}
+	_, err := ks.SetMany([]map[string]KeyStoreData{data})
+	require.NoError(t, err)
+
+	removed := ks.activeExpireCycle(time.Now())
+	assert.Equal(t, 1000, removed)
+	assert.Equal(t, 200, ks.Size())
+}
+
+func TestSetActiveExpiry(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	assert.Equal(t, defaultActiveExpiryInterval, ks.activeExpiryInterval())
+
+	ks.SetActiveExpiry(time.Second, 5)
+	assert.Equal(t, time.Second, ks.activeExpiryInterval())
+	assert.EqualValues(t, 5, ks.expiry.samples.Load())
+
+	ks.SetActiveExpiry(0, 0)
+	assert.Equal(t, time.Second, ks.activeExpiryInterval())
+	assert.EqualValues(t, 5, ks.expiry.samples.Load())
+}
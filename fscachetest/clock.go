@@ -0,0 +1,146 @@
+// Package fscachetest provides utilities to test code built on fs-cache.
+package fscachetest
+
+import (
+	"sync"
+	"time"
+
+	fscache "github.com/jiyamathias/fs-cache"
+)
+
+type (
+	// Clock is a fake fscache.Clock whose time only moves when Advance() or Set() is called.
+	// Pass it to fscache.New() with fscache.WithClock() to test time to live, expiry,
+	// persistence and Sync() intervals without sleeping.
+	Clock struct {
+		mu      sync.Mutex
+		cond    *sync.Cond
+		now     time.Time
+		tickers map[*ticker]struct{}
+	}
+
+	// ticker is a fscache.Ticker firing when its Clock is moved past its next tick
+	ticker struct {
+		clock  *Clock
+		c      chan time.Time
+		period time.Duration
+		next   time.Time
+	}
+)
+
+// NewClock returns a fake Clock set to the given time
+func NewClock(now time.Time) *Clock {
+	c := &Clock{
+		now:     now,
+		tickers: make(map[*ticker]struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// Now returns the current fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTicker returns a Ticker firing every d of fake time
+func (c *Clock) NewTicker(d time.Duration) fscache.Ticker {
+	if d <= 0 {
+		panic("fscachetest: non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &ticker{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers[t] = struct{}{}
+	c.cond.Broadcast()
+
+	return t
+}
+
+// Advance moves the time forward by d and fires the tickers that are due
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(c.now.Add(d))
+}
+
+// Set moves the time to t and fires the tickers that are due
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(t)
+}
+
+// BlockUntil blocks until n tickers are running on the clock. It lets a test
+// wait for the background jobs of a cache to start before moving the time.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.tickers) < n {
+		c.cond.Wait()
+	}
+}
+
+// set moves the time to now and fires the due tickers. Like a time.Ticker, a ticker
+// delivers a single tick when several are due and drops ticks for slow receivers.
+// The caller must hold the clock lock.
+func (c *Clock) set(now time.Time) {
+	c.now = now
+
+	for t := range c.tickers {
+		if t.next.After(now) {
+			continue
+		}
+
+		select {
+		case t.c <- now:
+		default:
+		}
+
+		// skip the ticks dropped in one step, a long Advance() must not loop over them
+		t.next = t.next.Add((now.Sub(t.next)/t.period + 1) * t.period)
+	}
+}
+
+// C returns the channel the ticks are delivered on
+func (t *ticker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop turns off the ticker
+func (t *ticker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	delete(t.clock.tickers, t)
+	t.clock.cond.Broadcast()
+}
+
+// Reset restarts the ticker with the period d from the current fake time
+func (t *ticker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("fscachetest: non-positive interval for Reset")
+	}
+
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	t.clock.tickers[t] = struct{}{}
+	t.clock.cond.Broadcast()
+}
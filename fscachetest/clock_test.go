@@ -0,0 +1,85 @@
+package fscachetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// received reports whether a tick is waiting on the ticker channel
+func received(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestClockNow(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), clock.Now())
+
+	clock.Set(start)
+	assert.Equal(t, start, clock.Now())
+}
+
+func TestClockTicker(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(999 * time.Millisecond)
+	assert.False(t, received(ticker.C()))
+
+	clock.Advance(time.Millisecond)
+	assert.True(t, received(ticker.C()))
+
+	// several due ticks are delivered as one
+	clock.Advance(5 * time.Second)
+	assert.True(t, received(ticker.C()))
+	assert.False(t, received(ticker.C()))
+
+	ticker.Reset(time.Minute)
+	clock.Advance(time.Second)
+	assert.False(t, received(ticker.C()))
+	clock.Advance(time.Minute)
+	assert.True(t, received(ticker.C()))
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	assert.False(t, received(ticker.C()))
+}
+
+func TestClockTickerLongAdvance(t *testing.T) {
+	clock := NewClock(time.Now())
+	ticker := clock.NewTicker(100 * time.Millisecond)
+
+	// the next tick stays aligned on the period
+	clock.Advance(24*time.Hour + 50*time.Millisecond)
+	assert.True(t, received(ticker.C()))
+
+	clock.Advance(49 * time.Millisecond)
+	assert.False(t, received(ticker.C()))
+	clock.Advance(time.Millisecond)
+	assert.True(t, received(ticker.C()))
+}
+
+func TestClockBlockUntil(t *testing.T) {
+	clock := NewClock(time.Now())
+
+	started := make(chan struct{})
+	go func() {
+		clock.NewTicker(time.Second)
+		clock.NewTicker(time.Minute)
+		close(started)
+	}()
+
+	clock.BlockUntil(2)
+	<-started
+}
@@ -0,0 +1,220 @@
+package fscache
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrFieldNotFound field not found
+var ErrFieldNotFound = errors.New("field not found")
+
+// keyStoreHash is the value of a key holding a hash, a map of fields to values
+type keyStoreHash struct {
+	fields map[string]any
+	// bytes is the estimated size of the fields and their values
+	bytes int64
+}
+
+// HSet() sets the fields of the hash stored at key and returns the number of fields added,
+// fields that already exist are updated. A missing key is created as a hash which never
+// expires, an existing one keeps its time to live.
+func (ks *KeyStore) HSet(key string, fields map[string]any) (int, error) {
+	var added int
+	err := updateCollection(ks, key, newKeyStoreHash, func(h *keyStoreHash) error {
+		for field, value := range fields {
+			if h.set(field, value) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// HGet() returns the value of a field of the hash stored at key.
+// It fails with ErrFieldNotFound if the key or the field doesn't exist.
+func (ks *KeyStore) HGet(key, field string) (any, error) {
+	var value any
+	found := false
+	err := readCollection(ks, key, func(h *keyStoreHash) {
+		value, found = h.fields[field]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrFieldNotFound
+	}
+
+	return value, nil
+}
+
+// HMGet() returns the values of the fields of the hash stored at key, in the order of
+// the fields. The value of a missing field is nil.
+func (ks *KeyStore) HMGet(key string, fields ...string) ([]any, error) {
+	values := make([]any, len(fields))
+	err := readCollection(ks, key, func(h *keyStoreHash) {
+		for i, field := range fields {
+			values[i] = h.fields[field]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// HDel() deletes fields from the hash stored at key and returns the number of fields deleted.
+// The key is deleted once its hash has no field left.
+func (ks *KeyStore) HDel(key string, fields ...string) (int, error) {
+	var deleted int
+	err := updateCollection(ks, key, nil, func(h *keyStoreHash) error {
+		for _, field := range fields {
+			if h.del(field) {
+				deleted++
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrKeyNotFound) {
+		return 0, nil
+	}
+
+	return deleted, err
+}
+
+// HExists() reports whether the hash stored at key has the field
+func (ks *KeyStore) HExists(key, field string) (bool, error) {
+	var found bool
+	err := readCollection(ks, key, func(h *keyStoreHash) {
+		_, found = h.fields[field]
+	})
+
+	return found, err
+}
+
+// HGetAll() returns a copy of the fields and values of the hash stored at key,
+// an empty map for a missing key
+func (ks *KeyStore) HGetAll(key string) (map[string]any, error) {
+	fields := map[string]any{}
+	err := readCollection(ks, key, func(h *keyStoreHash) {
+		fields = h.snapshot().(map[string]any)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// HKeys() returns the fields of the hash stored at key, in no particular order
+func (ks *KeyStore) HKeys(key string) ([]string, error) {
+	fields := []string{}
+	err := readCollection(ks, key, func(h *keyStoreHash) {
+		fields = make([]string, 0, len(h.fields))
+		for field := range h.fields {
+			fields = append(fields, field)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// HLen() returns the number of fields of the hash stored at key, zero for a missing key
+func (ks *KeyStore) HLen(key string) (int, error) {
+	var n int
+	err := readCollection(ks, key, func(h *keyStoreHash) {
+		n = len(h.fields)
+	})
+
+	return n, err
+}
+
+// HIncrBy() atomically increments the integer stored in a field of the hash stored at key
+// by delta and returns the result. A missing key or field is created and set to delta,
+// see IncrBy() for the values that can be incremented.
+func (ks *KeyStore) HIncrBy(key, field string, delta int64) (int64, error) {
+	var result int64
+	err := updateCollection(ks, key, newKeyStoreHash, func(h *keyStoreHash) error {
+		current, ok := h.fields[field]
+		if !ok {
+			current = int64(0)
+		}
+
+		value, sum, err := addInt(current, delta)
+		if err != nil {
+			return err
+		}
+
+		h.set(field, value)
+		result = sum
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+// newKeyStoreHash returns an empty hash
+func newKeyStoreHash() *keyStoreHash {
+	return &keyStoreHash{fields: make(map[string]any)}
+}
+
+// len returns the number of fields of the hash
+func (h *keyStoreHash) len() int {
+	return len(h.fields)
+}
+
+// snapshot returns a copy of the fields and values of the hash
+func (h *keyStoreHash) snapshot() any {
+	fields := make(map[string]any, len(h.fields))
+	for field, value := range h.fields {
+		fields[field] = value
+	}
+
+	return fields
+}
+
+// memSize returns the estimated bytes held by the hash
+func (h *keyStoreHash) memSize() int64 {
+	return int64(unsafe.Sizeof(*h)) + h.bytes
+}
+
+// set sets the value of a field and reports whether the field was added
+func (h *keyStoreHash) set(field string, value any) bool {
+	prev, exists := h.fields[field]
+	if exists {
+		h.bytes -= estimateValue(prev)
+	} else {
+		h.bytes += stringSize(field)
+	}
+
+	h.fields[field] = value
+	h.bytes += estimateValue(value)
+
+	return !exists
+}
+
+// del deletes a field and reports whether it existed
+func (h *keyStoreHash) del(field string) bool {
+	value, exists := h.fields[field]
+	if !exists {
+		return false
+	}
+
+	delete(h.fields, field)
+	h.bytes -= stringSize(field) + estimateValue(value)
+
+	return true
+}
@@ -0,0 +1,175 @@
+package fscache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHSetHGet(t *testing.T) {
+	ks := newTestKeyStore()
+
+	added, err := ks.HSet("session:jane", map[string]any{"name": "Jane", "visits": 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	// updating a field doesn't count as an addition
+	added, err = ks.HSet("session:jane", map[string]any{"name": "Jane Doe", "role": "admin"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	value, err := ks.HGet("session:jane", "name")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", value)
+
+	_, err = ks.HGet("session:jane", "missing")
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+
+	values, err := ks.HMGet("session:jane", "role", "missing", "visits")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"admin", nil, 1}, values)
+
+	all, err := ks.HGetAll("session:jane")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Jane Doe", "visits": 1, "role": "admin"}, all)
+
+	fields, err := ks.HKeys("session:jane")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"name", "visits", "role"}, fields)
+
+	n, err := ks.HLen("session:jane")
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	ok, err := ks.HExists("session:jane", "role")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Get() returns a copy of the hash
+	stored, err := ks.Get("session:jane")
+	require.NoError(t, err)
+	stored.(map[string]any)["name"] = "John"
+	value, err = ks.HGet("session:jane", "name")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", value)
+}
+
+func TestHashMissingKey(t *testing.T) {
+	ks := newTestKeyStore()
+
+	_, err := ks.HGet("missing", "field")
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+
+	values, err := ks.HMGet("missing", "a", "b")
+	require.NoError(t, err)
+	assert.Equal(t, []any{nil, nil}, values)
+
+	all, err := ks.HGetAll("missing")
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	fields, err := ks.HKeys("missing")
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	deleted, err := ks.HDel("missing", "a")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestHDel(t *testing.T) {
+	ks := newTestKeyStore()
+	before := ks.usage.bytes.Load()
+
+	_, err := ks.HSet("hash", map[string]any{"a": 1, "b": "two"})
+	require.NoError(t, err)
+
+	deleted, err := ks.HDel("hash", "a", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	// the key is deleted with its last field
+	deleted, err = ks.HDel("hash", "b")
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = ks.Get("hash")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, before, ks.usage.bytes.Load())
+}
+
+func TestHIncrBy(t *testing.T) {
+	ks := newTestKeyStore()
+
+	value, err := ks.HIncrBy("stats", "hits", 5)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, value)
+
+	value, err = ks.HIncrBy("stats", "hits", -2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, value)
+
+	_, err = ks.HSet("stats", map[string]any{"name": "home"})
+	require.NoError(t, err)
+	_, err = ks.HIncrBy("stats", "name", 1)
+	assert.ErrorIs(t, err, ErrNotInteger)
+
+	// a failed increment doesn't create the key
+	_, err = ks.HIncrBy("key1", "hits", 1)
+	assert.ErrorIs(t, err, ErrWrongType)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ks.HIncrBy("stats", "hits", 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	stored, err := ks.HGet("stats", "hits")
+	require.NoError(t, err)
+	assert.Equal(t, int64(103), stored)
+}
+
+func TestHashTTL(t *testing.T) {
+	ks := newTestKeyStore()
+	clock := newManualClock(time.Now())
+	ks.clock = clock
+
+	_, err := ks.HSet("hash", map[string]any{"a": 1})
+	require.NoError(t, err)
+	require.NoError(t, ks.Expire("hash", time.Minute))
+
+	// updating a field keeps the time to live of the whole hash
+	_, err = ks.HSet("hash", map[string]any{"b": 2})
+	require.NoError(t, err)
+	ttl, err := ks.TTL("hash")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	require.NoError(t, ks.ExpireAt("hash", clock.Now().Add(time.Millisecond)))
+	clock.Advance(time.Millisecond)
+
+	n, err := ks.HLen("hash")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestHashWrongType(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list", 1)
+	require.NoError(t, err)
+
+	_, err = ks.HSet("list", map[string]any{"a": 1})
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = ks.HGet("key1", "a")
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = ks.HGetAll("key2")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
@@ -0,0 +1,70 @@
+package fscache
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// TTLJitter spreads the expiry of the data objects set with the same time to live so that
+// they don't all expire at once. The time to live is moved by a random amount, up to the
+// spread either way, so it stays the same on average. The zero TTLJitter doesn't move it.
+type TTLJitter struct {
+	// Percent is the spread as a percentage of the time to live: 10 turns one hour into
+	// 54 to 66 minutes
+	Percent float64
+	// Range is the spread as a duration, it is used when Percent is zero
+	Range time.Duration
+}
+
+// SetTTLJitter() sets the jitter applied to the time to live of Set(), SetMany() and
+// OverWrite(), see TTLJitter. There is no jitter by default.
+func (ks *KeyStore) SetTTLJitter(jitter TTLJitter) {
+	ks.jitter.Store(&jitter)
+}
+
+// WithJitter() returns a view of the KeyStore applying another jitter than the one set with
+// SetTTLJitter(), to override it for some calls. The view shares the data and the settings
+// of the KeyStore, the zero TTLJitter disables the jitter.
+//
+//	_ = ks.WithJitter(fscache.TTLJitter{}).Set("exact", value, time.Minute)
+func (ks *KeyStore) WithJitter(jitter TTLJitter) *KeyStore {
+	view := &KeyStore{
+		keyStore: ks.keyStore,
+		jitter:   &atomic.Pointer[TTLJitter]{},
+		sliding:  ks.sliding,
+	}
+	view.jitter.Store(&jitter)
+
+	return view
+}
+
+// jitteredExpiry is expiresAt() with the jitter of the KeyStore applied to the ttl
+func (ks *KeyStore) jitteredExpiry(now time.Time, ttl []time.Duration) time.Time {
+	if len(ttl) == 0 || ttl[0] <= 0 {
+		return time.Time{}
+	}
+
+	return now.Add(ks.jittered(ttl[0]))
+}
+
+// jittered returns a positive ttl moved by the jitter of the KeyStore
+func (ks *KeyStore) jittered(ttl time.Duration) time.Duration {
+	jitter := ks.jitter.Load()
+	if jitter == nil || ttl <= 0 {
+		return ttl
+	}
+
+	spread := jitter.Range
+	if jitter.Percent > 0 {
+		spread = time.Duration(float64(ttl) * jitter.Percent / 100)
+	}
+
+	// the ttl must stay positive, a zero ttl would never expire
+	spread = min(spread, ttl-1)
+	if spread <= 0 {
+		return ttl
+	}
+
+	return ttl - spread + rand.N(2*spread+1)
+}
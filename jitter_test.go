@@ -0,0 +1,96 @@
+package fscache
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLJitter(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	ks.SetTTLJitter(TTLJitter{Percent: 10})
+
+	expiries := make(map[time.Time]struct{})
+	for i := 0; i < 100; i++ {
+		key := "key:" + strconv.Itoa(i)
+		require.NoError(t, ks.Set(key, i, time.Hour))
+
+		data, err := ks.GetData(key)
+		require.NoError(t, err)
+		ttl := data.Duration.Sub(now)
+		assert.GreaterOrEqual(t, ttl, 54*time.Minute)
+		assert.LessOrEqual(t, ttl, 66*time.Minute)
+		expiries[data.Duration] = struct{}{}
+	}
+	assert.Greater(t, len(expiries), 50)
+
+	// no time to live stays no time to live
+	require.NoError(t, ks.OverWrite("key:0", 0))
+	ttl, err := ks.TTL("key:0")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, ttl)
+}
+
+func TestTTLJitterRange(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	ks.SetTTLJitter(TTLJitter{Range: 30 * time.Second})
+
+	data := make([]map[string]KeyStoreData, 0, 100)
+	for i := 0; i < 100; i++ {
+		data = append(data, map[string]KeyStoreData{
+			"key:" + strconv.Itoa(i): {Value: i, Duration: now.Add(time.Minute)},
+		})
+	}
+	_, err := ks.SetMany(data)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		data, err := ks.GetData("key:" + strconv.Itoa(i))
+		require.NoError(t, err)
+		ttl := data.Duration.Sub(now)
+		assert.GreaterOrEqual(t, ttl, 30*time.Second)
+		assert.LessOrEqual(t, ttl, 90*time.Second)
+	}
+
+	// a spread over the time to live keeps it positive
+	require.NoError(t, ks.OverWrite("key:0", 0, time.Second))
+	ttl, err := ks.PTTL("key:0")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.Less(t, ttl, 2*time.Second)
+}
+
+func TestWithJitter(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	ks.SetTTLJitter(TTLJitter{Percent: 50})
+
+	// the view overrides the jitter and shares the data
+	exact := ks.WithJitter(TTLJitter{})
+	require.NoError(t, exact.Set("key4", "value4", time.Hour))
+
+	data, err := ks.GetData("key4")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), data.Duration)
+
+	assert.Equal(t, TTLJitter{Percent: 50}, *ks.jitter.Load())
+
+	// the view follows the settings changed on the KeyStore afterwards
+	ks.clock = fixedClock{now: now.Add(time.Minute)}
+	ks.logger = zerolog.New(io.Discard).Level(zerolog.DebugLevel)
+	require.NoError(t, exact.Set("key5", "value5", time.Hour))
+	data, err = ks.GetData("key5")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute+time.Hour), data.Duration)
+	assert.Equal(t, zerolog.DebugLevel, exact.logger.GetLevel())
+}
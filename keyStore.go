@@ -3,7 +3,20 @@ package fscache
 import (
 	"errors"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultShardCount is the number of shards the KeyStore storage is split into.
+	// It must be a power of two so a shard can be picked by masking the key hash.
+	defaultShardCount = 64
+
+	// NoExpiry is returned by TTL() and PTTL() for a data object that never expires
+	NoExpiry time.Duration = -1
 )
 
 var (
@@ -13,171 +26,628 @@ var (
 	ErrKeyExists = errors.New("key already exist")
 )
 
-// Set() adds a new data into the in-memory storage
-func (ks *KeyStore) Set(key string, value any, duration ...time.Duration) error {
-	ks.mu.Lock()
-	defer ks.mu.Unlock()
+type (
+	// keyStoreShard is a single partition of the KeyStore storage guarded by its own lock
+	keyStoreShard struct {
+		mu    sync.RWMutex
+		items map[string]KeyStoreData
+		// expires holds the keys of items with a duration, it is what the active expiry samples
+		expires map[string]struct{}
+		// softs orders the keys of items with a soft duration by the time they turn stale,
+		// it is what the refresh ahead pops the stale keys from
+		softs softQueue
+	}
 
-	for _, cache := range ks.storage {
-		if _, ok := cache[key]; ok {
-			return ErrKeyExists
+	// keyStoreUsage tracks the number of entries and the estimated bytes held by the
+	// KeyStore, the limits on them and the policy used to evict keys over those limits
+	keyStoreUsage struct {
+		entries    atomic.Int64
+		bytes      atomic.Int64
+		maxEntries atomic.Int64
+		maxBytes   atomic.Int64
+		policy     atomic.Pointer[policyHolder]
+	}
+
+	// policyHolder wraps an EvictionPolicy so it can be swapped atomically
+	policyHolder struct {
+		EvictionPolicy
+	}
+)
+
+// newKeyStore initializes a KeyStore with empty shards
+func newKeyStore(logger zerolog.Logger) KeyStore {
+	shards := make([]*keyStoreShard, defaultShardCount)
+	for i := range shards {
+		shards[i] = &keyStoreShard{
+			items:   make(map[string]KeyStoreData),
+			expires: make(map[string]struct{}),
 		}
 	}
 
-	var ttl time.Duration
-	if len(duration) > 0 {
-		ttl = duration[0]
+	expiry := &keyStoreExpiry{}
+	expiry.interval.Store(int64(defaultActiveExpiryInterval))
+	expiry.samples.Store(defaultActiveExpirySamples)
+
+	return KeyStore{
+		keyStore: &keyStore{
+			logger: logger,
+			shards: shards,
+			usage:  &keyStoreUsage{},
+			expiry: expiry,
+			clock:  systemClock{},
+			waiters: &keyWaiters{
+				waiting: make(map[string]map[chan struct{}]struct{}),
+			},
+			versions:  &atomic.Uint64{},
+			loads:     newLoadGroup(),
+			refresh:   newKeyStoreRefresh(),
+			lifecycle: newLifecycle(),
+			tags:      newTagIndex(),
+			evictions: newEvictHooks(),
+			sizes:     &sizeEstimator{},
+			stats:     &keyStoreStats{},
+		},
+		jitter: &atomic.Pointer[TTLJitter]{},
 	}
+}
 
-	fs := make(map[string]KeyStoreData)
-	fs[key] = KeyStoreData{
-		Value:    value,
-		Duration: time.Now().Add(ttl),
+// SetCapacity() bounds the storage to maxEntries entries and maxBytes estimated bytes.
+// A limit of zero or less leaves that dimension unbounded. Once over a limit, keys picked
+// by the eviction policy are removed, the least recently used keys by default. The policy
+// is told about every read and write, see WithCapacity() for what it costs the reads.
+func (ks *KeyStore) SetCapacity(maxEntries int, maxBytes int64) {
+	ks.usage.maxEntries.Store(int64(max(maxEntries, 0)))
+	ks.usage.maxBytes.Store(max(maxBytes, 0))
+
+	if ks.policy() == nil && (maxEntries > 0 || maxBytes > 0) {
+		ks.SetEvictionPolicy(NewLRUPolicy())
+		return
+	}
+
+	ks.evict()
+}
+
+// SetEvictionPolicy() sets the policy used to pick the keys to evict once the storage
+// is over capacity. Keys already stored are handed to the policy in no particular order,
+// the writes wait meanwhile so that no key is left out of the policy.
+func (ks *KeyStore) SetEvictionPolicy(policy EvictionPolicy) {
+	if policy == nil {
+		policy = NewLRUPolicy()
+	}
+
+	// the shards are locked in order, like lockShards() does
+	for _, shard := range ks.shards {
+		shard.mu.Lock()
+		for key := range shard.items {
+			policy.Add(key)
+		}
+	}
+	ks.usage.policy.Store(&policyHolder{policy})
+	for i := len(ks.shards) - 1; i >= 0; i-- {
+		ks.shards[i].mu.Unlock()
+	}
+
+	ks.evict()
+}
+
+// policy returns the eviction policy in use, nil if none was set
+func (ks *KeyStore) policy() EvictionPolicy {
+	if holder := ks.usage.policy.Load(); holder != nil {
+		return holder.EvictionPolicy
+	}
+
+	return nil
+}
+
+// store saves the data under key with a new version and accounts for it in the usage and
+// the eviction policy. The caller must hold the write lock of the shard.
+func (ks *KeyStore) store(shard *keyStoreShard, key string, data KeyStoreData) {
+	data.size = ks.estimateSize(key, data.Value)
+	data.Version = ks.versions.Add(1)
+	prev, exists := shard.items[key]
+	shard.items[key] = data
+	ks.tags.retag(key, prev.tags, data.tags)
+
+	if data.Duration.IsZero() {
+		delete(shard.expires, key)
+	} else {
+		shard.expires[key] = struct{}{}
+	}
+
+	if !data.SoftDuration.IsZero() && !data.SoftDuration.Equal(prev.SoftDuration) {
+		shard.pushSoft(softEntry{at: data.SoftDuration, key: key})
+	}
+
+	policy := ks.policy()
+	if exists {
+		ks.usage.bytes.Add(data.size - prev.size)
+		if policy != nil {
+			policy.Access(key)
+		}
+		return
+	}
+
+	ks.usage.entries.Add(1)
+	ks.usage.bytes.Add(data.size)
+	if policy != nil {
+		policy.Add(key)
+	}
+}
+
+// remove deletes the key and releases it from the usage and the eviction policy.
+// The caller must hold the write lock of the shard.
+func (ks *KeyStore) remove(shard *keyStoreShard, key string) (KeyStoreData, bool) {
+	data, ok := ks.drop(shard, key)
+	if ok {
+		if policy := ks.policy(); policy != nil {
+			policy.Remove(key)
+		}
+	}
+
+	return data, ok
+}
+
+// drop deletes the key and releases it from the usage without telling the eviction policy.
+// The caller must hold the write lock of the shard.
+func (ks *KeyStore) drop(shard *keyStoreShard, key string) (KeyStoreData, bool) {
+	data, ok := shard.items[key]
+	if !ok {
+		return data, false
+	}
+
+	delete(shard.items, key)
+	ks.tags.retag(key, data.tags, nil)
+	delete(shard.expires, key)
+	if len(shard.items) == 0 {
+		// nothing is left to refresh in the shard
+		shard.softs = nil
+	}
+	ks.usage.entries.Add(-1)
+	ks.usage.bytes.Add(-data.size)
+
+	return data, true
+}
+
+// access records a read of the key with the eviction policy
+func (ks *KeyStore) access(key string) {
+	if policy := ks.policy(); policy != nil {
+		policy.Access(key)
+	}
+}
+
+// overCapacity reports whether the storage holds more than its limits allow
+func (ks *KeyStore) overCapacity() bool {
+	if maxEntries := ks.usage.maxEntries.Load(); maxEntries > 0 && ks.usage.entries.Load() > maxEntries {
+		return true
+	}
+
+	if maxBytes := ks.usage.maxBytes.Load(); maxBytes > 0 && ks.usage.bytes.Load() > maxBytes {
+		return true
+	}
+
+	return false
+}
+
+// evict removes the keys picked by the eviction policy until the storage fits its limits.
+// It must be called without holding any shard lock.
+func (ks *KeyStore) evict() {
+	policy := ks.policy()
+	if policy == nil {
+		return
+	}
+
+	for ks.overCapacity() {
+		key, ok := policy.Victim()
+		if !ok {
+			return
+		}
+
+		shard := ks.shard(key)
+		shard.mu.Lock()
+		if data, ok := ks.drop(shard, key); ok {
+			ks.stats.evictions.Add(1)
+			ks.evicted(key, data.Value, EvictEvicted)
+			ks.logger.Info().Msgf("data object [%v] got evicted", key)
+		}
+		shard.mu.Unlock()
+	}
+}
+
+// shardIndex returns the index of the shard a key belongs to.
+// It hashes the key with 32-bit FNV-1a inline so lookups don't allocate.
+func (ks *KeyStore) shardIndex(key string) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+
+	return int(hash & uint32(len(ks.shards)-1))
+}
+
+// shard returns the shard a key belongs to
+func (ks *KeyStore) shard(key string) *keyStoreShard {
+	return ks.shards[ks.shardIndex(key)]
+}
+
+// Set() adds a new data into the in-memory storage.
+// The optional duration sets its time to live, without it or with a zero or negative
+// duration the data never expires. The jitter set with SetTTLJitter() applies to it.
+func (ks *KeyStore) Set(key string, value any, duration ...time.Duration) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	if _, ok := ks.live(shard, key, now); ok {
+		shard.mu.Unlock()
+		return ErrKeyExists
 	}
 
-	ks.storage = append(ks.storage, fs)
+	ks.store(shard, key, ks.slidingWrite(now, KeyStoreData{
+		Value:    value,
+		Duration: ks.jitteredExpiry(now, duration),
+	}))
+	ks.notify(EventSet, key, nil, value)
+	shard.mu.Unlock()
+
+	ks.evict()
 
 	return nil
 }
 
-// SetMany() sets many data objects into memory for later access
+// SetMany() sets many data objects into memory for later access.
+// The jitter set with SetTTLJitter() applies to the time left until their Duration.
 func (ks *KeyStore) SetMany(data []map[string]KeyStoreData) ([]map[string]any, error) {
-	ks.mu.Lock()
-	defer ks.mu.Unlock()
+	now := ks.clock.Now()
+	for _, cache := range data {
+		for key, value := range cache {
+			if value.Duration.After(now) {
+				value.Duration = now.Add(ks.jittered(value.Duration.Sub(now)))
+			}
+			value = ks.slidingWrite(now, value)
+
+			shard := ks.shard(key)
+			shard.mu.Lock()
+			prev, existed := ks.live(shard, key, now)
+			ks.store(shard, key, value)
+			if existed {
+				ks.notify(EventOverWrite, key, prev.Value, value.Value)
+				ks.evicted(key, prev.Value, EvictReplaced)
+			} else {
+				ks.notify(EventSet, key, nil, value.Value)
+			}
+			shard.mu.Unlock()
+		}
+	}
+
+	ks.evict()
 
-	ks.storage = append(ks.storage, data...)
 	KeyValuePairs := ks.KeyValuePairs()
 
 	return KeyValuePairs, nil
 }
 
-// Get() retrieves a data from the in-memory storage
+// Get() retrieves a data from the in-memory storage.
+// It resets the time to live of a data set with SetSliding().
 func (ks *KeyStore) Get(key string) (any, error) {
-	for _, cache := range ks.storage {
-		if val, ok := cache[key]; ok {
-			return val.Value, nil
-		}
+	value, err := ks.get(key)
+	ks.stats.lookup(err == nil)
+
+	return value, err
+}
+
+// get is Get() without counting the read in the statistics
+func (ks *KeyStore) get(key string) (any, error) {
+	shard := ks.shard(key)
+	shard.mu.RLock()
+	val, ok := shard.items[key]
+	if !ok {
+		shard.mu.RUnlock()
+		return nil, ErrKeyNotFound
 	}
 
-	return nil, ErrKeyNotFound
+	now := ks.clock.Now()
+	if val.expired(now) {
+		shard.mu.RUnlock()
+		ks.expire(key)
+		return nil, ErrKeyNotFound
+	}
+
+	value := exposed(val.Value)
+	shard.mu.RUnlock()
+
+	ks.access(key)
+	if val.sliding > 0 {
+		ks.slide(key, now)
+	}
+	if val.stale(now) {
+		ks.revalidate(key)
+	}
+
+	return value, nil
 }
 
 // GetMany() retrieves data with matching keys from the in-memory storage
 func (ks *KeyStore) GetMany(keys []string) []map[string]any {
 	keyValuePairs := []map[string]any{}
-	for _, cache := range ks.storage {
-		data := make(map[string]any)
-		for _, key := range keys {
-			if val, ok := cache[key]; ok {
-				data[key] = val.Value
-				keyValuePairs = append(keyValuePairs, data)
-			}
+	for _, key := range keys {
+		val, err := ks.Get(key)
+		if err != nil {
+			continue
 		}
+
+		keyValuePairs = append(keyValuePairs, map[string]any{key: val})
 	}
 
 	return keyValuePairs
 }
 
+// GetEx() retrieves a data from the in-memory storage and updates its time to live.
+// A positive ttl sets a new time to live, a zero or negative ttl makes the data never expire.
+func (ks *KeyStore) GetEx(key string, ttl time.Duration) (any, error) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := ks.clock.Now()
+	data, ok := ks.live(shard, key, now)
+	ks.stats.lookup(ok)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	data.Duration = expiresAt(now, []time.Duration{ttl})
+	data.sliding, data.hardTTL = 0, 0
+	ks.store(shard, key, data)
+	ks.notify(EventTTL, key, data.Value, data.Value)
+
+	return exposed(data.Value), nil
+}
+
+// Expire() sets the time to live of a data. Like Redis, a zero or negative ttl deletes the data.
+func (ks *KeyStore) Expire(key string, ttl time.Duration) error {
+	return ks.ExpireAt(key, ks.clock.Now().Add(ttl))
+}
+
+// ExpireAt() sets the time a data expires at. Like Redis, a time in the past deletes the data.
+func (ks *KeyStore) ExpireAt(key string, at time.Time) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := ks.clock.Now()
+	data, ok := ks.live(shard, key, now)
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	if !now.Before(at) {
+		ks.remove(shard, key)
+		ks.notify(EventDel, key, data.Value, nil)
+		ks.evicted(key, data.Value, EvictDeleted)
+		return nil
+	}
+
+	data.Duration = at
+	data.sliding, data.hardTTL = 0, 0
+	ks.store(shard, key, data)
+	ks.notify(EventTTL, key, data.Value, data.Value)
+
+	return nil
+}
+
+// Persist() removes the time to live of a data so that it never expires
+func (ks *KeyStore) Persist(key string) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	if data.Duration.IsZero() {
+		return nil
+	}
+
+	data.Duration = time.Time{}
+	data.sliding, data.hardTTL = 0, 0
+	ks.store(shard, key, data)
+	ks.notify(EventTTL, key, data.Value, data.Value)
+
+	return nil
+}
+
+// TTL() returns the remaining time to live of a data rounded to the second,
+// or NoExpiry if the data never expires
+func (ks *KeyStore) TTL(key string) (time.Duration, error) {
+	ttl, err := ks.PTTL(key)
+	if err != nil || ttl == NoExpiry {
+		return ttl, err
+	}
+
+	return ttl.Round(time.Second), nil
+}
+
+// PTTL() returns the remaining time to live of a data rounded to the millisecond,
+// or NoExpiry if the data never expires
+func (ks *KeyStore) PTTL(key string) (time.Duration, error) {
+	shard := ks.shard(key)
+	shard.mu.RLock()
+	data, ok := shard.items[key]
+	shard.mu.RUnlock()
+
+	now := ks.clock.Now()
+	if !ok || data.expired(now) {
+		return 0, ErrKeyNotFound
+	}
+
+	if data.Duration.IsZero() {
+		return NoExpiry, nil
+	}
+
+	return data.Duration.Sub(now).Round(time.Millisecond), nil
+}
+
 // Del() deletes a data from the in-memory storage
 func (ks *KeyStore) Del(key string) error {
-	ks.mu.Lock()
-	defer ks.mu.Unlock()
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	for index, cache := range ks.storage {
-		if _, ok := cache[key]; ok {
-			ks.storage = append(ks.storage[:index], ks.storage[index+1:]...)
-			return nil
-		}
+	data, ok := ks.live(shard, key, ks.clock.Now())
+	if !ok {
+		return ErrKeyNotFound
 	}
 
-	return ErrKeyNotFound
+	ks.remove(shard, key)
+	ks.notify(EventDel, key, data.Value, nil)
+	ks.evicted(key, data.Value, EvictDeleted)
+
+	return nil
 }
 
 // Clear() deletes all data from the in-memory storage
 func (ks *KeyStore) Clear() error {
-	ks.storage = ks.storage[:0]
+	for _, shard := range ks.shards {
+		shard.mu.Lock()
+		for key := range shard.items {
+			data, _ := ks.remove(shard, key)
+			ks.notify(EventDel, key, data.Value, nil)
+			ks.evicted(key, data.Value, EvictCleared)
+		}
+		shard.mu.Unlock()
+	}
+
 	return nil
 }
 
-// Size() retrieves the total data objects in the in-memory storage
+// Size() retrieves the total data objects in the in-memory storage.
+// Expired data objects are counted until they get reclaimed.
 func (ks *KeyStore) Size() int {
-	return len(ks.storage)
+	return int(ks.usage.entries.Load())
 }
 
-// OverWrite() updates an already set value using it key
+// OverWrite() updates an already set value using it key.
+// The jitter set with SetTTLJitter() applies to the optional duration.
 func (ks *KeyStore) OverWrite(key string, value any, duration ...time.Duration) error {
-	ks.mu.Lock()
-	defer ks.mu.Unlock()
-
-	var isFound bool
-	for index, cache := range ks.storage {
-		if _, ok := cache[key]; ok {
-			isFound = true
-			ks.storage = append(ks.storage[:index], ks.storage[index+1:]...)
-		}
-	}
+	shard := ks.shard(key)
+	shard.mu.Lock()
 
-	if !isFound {
+	now := ks.clock.Now()
+	prev, ok := ks.live(shard, key, now)
+	if !ok {
+		shard.mu.Unlock()
 		return ErrKeyNotFound
 	}
 
-	var ttl time.Duration
-	if len(duration) > 0 {
-		ttl = duration[0]
-	}
-
-	fs := make(map[string]KeyStoreData)
-	fs[key] = KeyStoreData{
+	ks.store(shard, key, ks.slidingWrite(now, KeyStoreData{
 		Value:    value,
-		Duration: time.Now().Add(ttl),
-	}
+		Duration: ks.jitteredExpiry(now, duration),
+	}))
+	ks.notify(EventOverWrite, key, prev.Value, value)
+	ks.evicted(key, prev.Value, EvictReplaced)
+	shard.mu.Unlock()
 
-	ks.storage = append(ks.storage, fs)
+	ks.evict()
 
 	return nil
 }
 
 // OverWriteWithKey() updates an already set value and key using the previously set key
 func (ks *KeyStore) OverWriteWithKey(prevkey, newKey string, value any, duration ...time.Duration) error {
-	ks.mu.Lock()
-	defer ks.mu.Unlock()
-
-	var isFound bool
-	for index, cache := range ks.storage {
-		if _, ok := cache[prevkey]; ok {
-			isFound = true
-			ks.storage = append(ks.storage[:index], ks.storage[index+1:]...)
+	prevShard, newShard := ks.shard(prevkey), ks.shard(newKey)
+	unlock := ks.lockShards(prevkey, newKey)
+
+	now := ks.clock.Now()
+	prev, ok := ks.live(prevShard, prevkey, now)
+	if !ok {
+		unlock()
+		return ErrKeyNotFound
+	}
+
+	ks.remove(prevShard, prevkey)
+	replaced, replacing := ks.live(newShard, newKey, now)
+	ks.store(newShard, newKey, ks.slidingWrite(now, KeyStoreData{
+		Value:    value,
+		Duration: expiresAt(now, duration),
+	}))
+
+	if prevkey == newKey {
+		ks.notify(EventOverWrite, newKey, prev.Value, value)
+		ks.evicted(newKey, prev.Value, EvictReplaced)
+	} else {
+		ks.notify(EventDel, prevkey, prev.Value, nil)
+		ks.evicted(prevkey, prev.Value, EvictDeleted)
+		if replacing {
+			ks.notify(EventOverWrite, newKey, replaced.Value, value)
+			ks.evicted(newKey, replaced.Value, EvictReplaced)
+		} else {
+			ks.notify(EventSet, newKey, nil, value)
 		}
 	}
+	unlock()
 
-	if !isFound {
-		return ErrKeyNotFound
+	ks.evict()
+
+	return nil
+}
+
+// lockShards write-locks the distinct shards holding the given keys in ascending
+// shard order, so that concurrent multi-key operations can't deadlock each other.
+// It returns a function that releases the locks.
+func (ks *KeyStore) lockShards(keys ...string) func() {
+	locked := make([]bool, len(ks.shards))
+	for _, key := range keys {
+		locked[ks.shardIndex(key)] = true
 	}
 
-	var ttl time.Duration
-	if len(duration) > 0 {
-		ttl = duration[0]
+	for i, ok := range locked {
+		if ok {
+			ks.shards[i].mu.Lock()
+		}
 	}
 
-	fs := make(map[string]KeyStoreData)
-	fs[newKey] = KeyStoreData{
-		Value:    value,
-		Duration: time.Now().Add(ttl),
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			if locked[i] {
+				ks.shards[i].mu.Unlock()
+			}
+		}
 	}
+}
 
-	ks.storage = append(ks.storage, fs)
+// rlockShards read-locks the distinct shards holding the given keys in ascending
+// shard order, see lockShards(). It returns a function that releases the locks.
+func (ks *KeyStore) rlockShards(keys ...string) func() {
+	locked := make([]bool, len(ks.shards))
+	for _, key := range keys {
+		locked[ks.shardIndex(key)] = true
+	}
 
-	return nil
+	for i, ok := range locked {
+		if ok {
+			ks.shards[i].mu.RLock()
+		}
+	}
+
+	return func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			if locked[i] {
+				ks.shards[i].mu.RUnlock()
+			}
+		}
+	}
 }
 
 // Keys() returns all the keys in the storage
 func (ks *KeyStore) Keys() []string {
 	var keys []string
-	for _, cache := range ks.storage {
-		for key := range cache {
-			keys = append(keys, key)
-		}
-	}
+	ks.forEach(func(key string, _ KeyStoreData) bool {
+		keys = append(keys, key)
+		return true
+	})
 
 	return keys
 }
@@ -185,22 +655,23 @@ func (ks *KeyStore) Keys() []string {
 // Values() returns all the values in the storage
 func (ks *KeyStore) Values() []any {
 	var values []any
-	for _, cache := range ks.storage {
-		for _, v := range cache {
-			values = append(values, v.Value)
-		}
-	}
+	ks.forEach(func(_ string, data KeyStoreData) bool {
+		values = append(values, exposed(data.Value))
+		return true
+	})
 
 	return values
 }
 
 // TypeOf() returns the data type of a value
 func (ks *KeyStore) TypeOf(key string) (string, error) {
-	for _, cache := range ks.storage {
-		value, ok := cache[key]
-		if ok {
-			return reflect.TypeOf(value.Value).String(), nil
-		}
+	shard := ks.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	value, ok := shard.items[key]
+	if ok && !value.expired(ks.clock.Now()) {
+		return reflect.TypeOf(exposed(value.Value)).String(), nil
 	}
 
 	return "", ErrKeyNotFound
@@ -209,15 +680,26 @@ func (ks *KeyStore) TypeOf(key string) (string, error) {
 // KeyValuePairs() returns an array of key value pairs of all the data in the storage
 func (ks *KeyStore) KeyValuePairs() []map[string]any {
 	keyValuePairs := []map[string]any{}
+	ks.forEach(func(key string, data KeyStoreData) bool {
+		keyValuePairs = append(keyValuePairs, map[string]any{key: exposed(data.Value)})
+		return true
+	})
 
-	for _, v := range ks.storage {
-		data := make(map[string]any)
-		for key, value := range v {
-			data[key] = value.Value
-		}
+	return keyValuePairs
+}
 
-		keyValuePairs = append(keyValuePairs, data)
+// forEach calls fn with every data object that is not expired, holding the read lock
+// of a single shard at a time. The iteration stops once fn returns false.
+func (ks *KeyStore) forEach(fn func(key string, data KeyStoreData) bool) {
+	now := ks.clock.Now()
+	for _, shard := range ks.shards {
+		shard.mu.RLock()
+		for key, data := range shard.items {
+			if !data.expired(now) && !fn(key, data) {
+				shard.mu.RUnlock()
+				return
+			}
+		}
+		shard.mu.RUnlock()
 	}
-
-	return keyValuePairs
 }
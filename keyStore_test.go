@@ -3,10 +3,13 @@ package fscache
 import (
 	"bytes"
 	"io"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,11 +38,16 @@ var keyStoreTestCases = []map[string]KeyStoreData{
 	},
 }
 
+// newTestKeyStore returns a KeyStore loaded with the keyStoreTestCases
+func newTestKeyStore() KeyStore {
+	ks := newKeyStore(zerolog.Nop())
+	_, _ = ks.SetMany(keyStoreTestCases)
+
+	return ks
+}
+
 func TestSet(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -52,10 +60,7 @@ func TestSet(t *testing.T) {
 }
 
 func TestGet(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -69,10 +74,7 @@ func TestGet(t *testing.T) {
 }
 
 func TestDel(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -82,10 +84,7 @@ func TestDel(t *testing.T) {
 }
 
 func TestClear(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -95,10 +94,7 @@ func TestClear(t *testing.T) {
 }
 
 func TestSize(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -108,10 +104,7 @@ func TestSize(t *testing.T) {
 }
 
 func TestDebug(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -132,10 +125,7 @@ func TestDebug(t *testing.T) {
 }
 
 func TestOverWrite(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -148,10 +138,7 @@ func TestOverWrite(t *testing.T) {
 }
 
 func TestOverWriteWithKey(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -164,10 +151,7 @@ func TestOverWriteWithKey(t *testing.T) {
 }
 
 func TestTypeOf(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -181,10 +165,7 @@ func TestTypeOf(t *testing.T) {
 }
 
 func TestKeyValuePairs(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -194,10 +175,7 @@ func TestKeyValuePairs(t *testing.T) {
 }
 
 func TestSetMany(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -220,10 +198,7 @@ func TestSetMany(t *testing.T) {
 }
 
 func TestGetMany(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -235,10 +210,7 @@ func TestGetMany(t *testing.T) {
 }
 
 func TestKeys(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -248,10 +220,7 @@ func TestKeys(t *testing.T) {
 }
 
 func TestValues(t *testing.T) {
-	md := KeyStore{
-		mu:      &sync.RWMutex{},
-		storage: keyStoreTestCases,
-	}
+	md := newTestKeyStore()
 	ch := Cache{
 		KeyStoreInstance: md,
 	}
@@ -259,3 +228,185 @@ func TestValues(t *testing.T) {
 	values := ch.KeyStore().Values()
 	assert.NotNil(t, values)
 }
+
+func TestConcurrentAccess(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				key := strconv.Itoa(worker) + ":" + strconv.Itoa(j)
+				assert.NoError(t, ks.Set(key, j, time.Minute))
+				value, err := ks.Get(key)
+				assert.NoError(t, err)
+				assert.Equal(t, j, value)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8*500, ks.Size())
+	assert.Len(t, ks.Keys(), 8*500)
+
+	require.NoError(t, ks.OverWriteWithKey("0:0", "moved", "value"))
+	_, err := ks.Get("0:0")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	value, err := ks.Get("moved")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	require.NoError(t, ks.Clear())
+	assert.Zero(t, ks.Size())
+}
+
+// benchmarkKeyStore returns a KeyStore pre-populated with n keys
+func benchmarkKeyStore(n int) *KeyStore {
+	ks := newKeyStore(zerolog.Nop())
+	for i := 0; i < n; i++ {
+		_ = ks.Set("key"+strconv.Itoa(i), i, time.Hour)
+	}
+
+	return &ks
+}
+
+func BenchmarkKeyStoreGet(b *testing.B) {
+	ks := benchmarkKeyStore(100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = ks.Get("key" + strconv.Itoa(i%100000))
+	}
+}
+
+func BenchmarkKeyStoreSet(b *testing.B) {
+	ks := benchmarkKeyStore(100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = ks.Set("new"+strconv.Itoa(i), i, time.Hour)
+	}
+}
+
+func BenchmarkKeyStoreGetParallel(b *testing.B) {
+	ks := benchmarkKeyStore(100000)
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = ks.Get("key" + strconv.Itoa(i%100000))
+			i++
+		}
+	})
+}
+
+func BenchmarkKeyStoreSetParallel(b *testing.B) {
+	ks := benchmarkKeyStore(100000)
+	b.ResetTimer()
+
+	var n atomic.Int64
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = ks.Set("new"+strconv.FormatInt(n.Add(1), 10), 1, time.Hour)
+		}
+	})
+}
+
+func TestExpire(t *testing.T) {
+	ks := newTestKeyStore()
+
+	require.NoError(t, ks.Expire("key2", time.Minute))
+	ttl, err := ks.TTL("key2")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	// a zero or negative ttl deletes the data like Redis does
+	require.NoError(t, ks.Expire("key2", 0))
+	_, err = ks.Get("key2")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.ErrorIs(t, ks.Expire("missing_key", time.Minute), ErrKeyNotFound)
+}
+
+func TestExpireAt(t *testing.T) {
+	ks := newTestKeyStore()
+
+	require.NoError(t, ks.ExpireAt("key3", time.Now().Add(time.Hour)))
+	ttl, err := ks.TTL("key3")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	require.NoError(t, ks.ExpireAt("key3", time.Now().Add(-time.Second)))
+	_, err = ks.Get("key3")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.ErrorIs(t, ks.ExpireAt("missing_key", time.Now()), ErrKeyNotFound)
+}
+
+func TestTTL(t *testing.T) {
+	ks := newTestKeyStore()
+	require.NoError(t, ks.Set("persistent", "value"))
+	require.NoError(t, ks.Set("volatile", "value", 1500*time.Millisecond))
+
+	ttl, err := ks.TTL("persistent")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, ttl)
+
+	ttl, err = ks.TTL("volatile")
+	require.NoError(t, err)
+	assert.Contains(t, []time.Duration{time.Second, 2 * time.Second}, ttl)
+
+	pttl, err := ks.PTTL("volatile")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, pttl, 1500*time.Millisecond)
+	assert.Greater(t, pttl, time.Second)
+
+	pttl, err = ks.PTTL("persistent")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, pttl)
+
+	_, err = ks.TTL("missing_key")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = ks.PTTL("missing_key")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestPersist(t *testing.T) {
+	ks := newTestKeyStore()
+
+	require.NoError(t, ks.Persist("key1"))
+	ttl, err := ks.TTL("key1")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, ttl)
+
+	// persisting a data without a time to live is a no-op
+	require.NoError(t, ks.Persist("key1"))
+
+	require.ErrorIs(t, ks.Persist("missing_key"), ErrKeyNotFound)
+}
+
+func TestGetEx(t *testing.T) {
+	ks := newTestKeyStore()
+
+	value, err := ks.GetEx("key2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 10, value)
+
+	ttl, err := ks.TTL("key2")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	value, err = ks.GetEx("key2", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 10, value)
+
+	ttl, err = ks.TTL("key2")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, ttl)
+
+	_, err = ks.GetEx("missing_key", time.Minute)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
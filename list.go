@@ -0,0 +1,429 @@
+package fscache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// minListCapacity is the smallest ring buffer a list allocates
+const minListCapacity = 8
+
+// ErrIndexOutOfRange index out of range
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+type (
+	// keyStoreList is the value of a key holding a list. It is a deque backed by a ring
+	// buffer, so pushing and popping at both ends as well as indexing are O(1).
+	keyStoreList struct {
+		buf  []any
+		head int
+		n    int
+		// bytes is the estimated size of the elements
+		bytes int64
+	}
+
+	// keyWaiters wakes up the callers blocked on keys, like BLPop() waiting for a push
+	keyWaiters struct {
+		mu      sync.Mutex
+		waiting map[string]map[chan struct{}]struct{}
+	}
+)
+
+// LPush() inserts the values at the head of the list stored at key and returns the length
+// of the list. Like Redis, the values are inserted one after the other from the leftmost
+// to the rightmost, and a missing key is created as a list which never expires.
+func (ks *KeyStore) LPush(key string, values ...any) (int, error) {
+	return ks.push(key, values, (*keyStoreList).pushFront)
+}
+
+// RPush() inserts the values at the tail of the list stored at key and returns the length
+// of the list. A missing key is created as a list which never expires.
+func (ks *KeyStore) RPush(key string, values ...any) (int, error) {
+	return ks.push(key, values, (*keyStoreList).pushBack)
+}
+
+// LPop() removes and returns the first element of the list stored at key.
+// The key is deleted once its list is empty.
+func (ks *KeyStore) LPop(key string) (any, error) {
+	return ks.pop(key, (*keyStoreList).popFront)
+}
+
+// RPop() removes and returns the last element of the list stored at key.
+// The key is deleted once its list is empty.
+func (ks *KeyStore) RPop(key string) (any, error) {
+	return ks.pop(key, (*keyStoreList).popBack)
+}
+
+// BLPop() is the blocking version of LPop(): it pops the first element of the first
+// non-empty list among keys, in order, and otherwise waits for a value to be pushed
+// to one of them. It returns the key the value was popped from, or the error of ctx
+// once it is done, e.g. context.DeadlineExceeded to implement a timeout.
+func (ks *KeyStore) BLPop(ctx context.Context, keys ...string) (string, any, error) {
+	return ks.blockingPop(ctx, keys, ks.LPop)
+}
+
+// BRPop() is the blocking version of RPop(), see BLPop()
+func (ks *KeyStore) BRPop(ctx context.Context, keys ...string) (string, any, error) {
+	return ks.blockingPop(ctx, keys, ks.RPop)
+}
+
+// LRange() returns the elements of the list stored at key from start to stop, both included.
+// Like Redis, negative indexes count from the tail, -1 being the last element, and out of range
+// indexes are clamped. A missing key is an empty list.
+func (ks *KeyStore) LRange(key string, start, stop int) ([]any, error) {
+	values := []any{}
+	err := readCollection(ks, key, func(l *keyStoreList) {
+		if start, stop, ok := listRange(start, stop, l.n); ok {
+			values = l.slice(start, stop)
+		}
+	})
+
+	return values, err
+}
+
+// LLen() returns the length of the list stored at key, zero for a missing key
+func (ks *KeyStore) LLen(key string) (int, error) {
+	var n int
+	err := readCollection(ks, key, func(l *keyStoreList) {
+		n = l.n
+	})
+
+	return n, err
+}
+
+// LIndex() returns the element at index in the list stored at key, negative indexes
+// count from the tail. It fails with ErrIndexOutOfRange if there is no such element.
+func (ks *KeyStore) LIndex(key string, index int) (any, error) {
+	var value any
+	found := false
+	err := readCollection(ks, key, func(l *keyStoreList) {
+		if index < 0 {
+			index += l.n
+		}
+		if index >= 0 && index < l.n {
+			value, found = l.at(index), true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrIndexOutOfRange
+	}
+
+	return value, nil
+}
+
+// LTrim() trims the list stored at key so that it only holds the elements from start
+// to stop, both included, see LRange() for the indexes. The key is deleted if no element is left.
+func (ks *KeyStore) LTrim(key string, start, stop int) error {
+	err := updateCollection(ks, key, nil, func(l *keyStoreList) error {
+		start, stop, ok := listRange(start, stop, l.n)
+		if !ok {
+			l.reset(nil)
+			return nil
+		}
+
+		l.reset(l.slice(start, stop))
+		return nil
+	})
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// LRem() removes the elements equal to value from the list stored at key and returns
+// how many were removed. Like Redis, a positive count removes up to count elements from
+// the head, a negative count up to -count elements from the tail and zero all of them.
+// Elements are compared with reflect.DeepEqual().
+func (ks *KeyStore) LRem(key string, count int, value any) (int, error) {
+	var removed int
+	err := updateCollection(ks, key, nil, func(l *keyStoreList) error {
+		removed = l.remove(count, value)
+		return nil
+	})
+	if errors.Is(err, ErrKeyNotFound) {
+		return 0, nil
+	}
+
+	return removed, err
+}
+
+// push inserts the values into the list stored at key and wakes up the callers blocked on it
+func (ks *KeyStore) push(key string, values []any, insert func(l *keyStoreList, value any)) (int, error) {
+	var n int
+	err := updateCollection(ks, key, newKeyStoreList, func(l *keyStoreList) error {
+		for _, value := range values {
+			insert(l, value)
+		}
+		n = l.n
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		ks.waiters.notify(key)
+	}
+
+	return n, nil
+}
+
+// pop removes an element from the list stored at key
+func (ks *KeyStore) pop(key string, remove func(l *keyStoreList) any) (any, error) {
+	var value any
+	err := updateCollection(ks, key, nil, func(l *keyStoreList) error {
+		value = remove(l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// blockingPop pops from the first non-empty list among keys, waiting for a push until ctx is done.
+// The caller registers as a waiter before trying to pop, so a push happening in between isn't missed.
+func (ks *KeyStore) blockingPop(ctx context.Context, keys []string, pop func(key string) (any, error)) (string, any, error) {
+	wake := make(chan struct{}, 1)
+	ks.waiters.add(wake, keys)
+	defer ks.waiters.remove(wake, keys)
+
+	for {
+		for _, key := range keys {
+			value, err := pop(key)
+			if err == nil {
+				return key, value, nil
+			}
+			if !errors.Is(err, ErrKeyNotFound) {
+				return "", nil, err
+			}
+		}
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+	}
+}
+
+// listRange converts Redis style start and stop indexes, negative ones counting from the
+// tail, to a range of a list of length n. It reports false if the range is empty.
+func listRange(start, stop, n int) (int, int, bool) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	if start > stop {
+		return 0, 0, false
+	}
+
+	return start, stop, true
+}
+
+// newKeyStoreList returns an empty list
+func newKeyStoreList() *keyStoreList {
+	return &keyStoreList{}
+}
+
+// len returns the number of elements of the list
+func (l *keyStoreList) len() int {
+	return l.n
+}
+
+// snapshot returns the elements of the list as a slice
+func (l *keyStoreList) snapshot() any {
+	if l.n == 0 {
+		return []any{}
+	}
+
+	return l.slice(0, l.n-1)
+}
+
+// memSize returns the estimated bytes held by the list
+func (l *keyStoreList) memSize() int64 {
+	return int64(unsafe.Sizeof(*l)) + int64(cap(l.buf))*int64(unsafe.Sizeof(any(nil))) + l.bytes
+}
+
+// at returns the element at index i, which must be in range
+func (l *keyStoreList) at(i int) any {
+	return l.buf[(l.head+i)%len(l.buf)]
+}
+
+// slice returns a copy of the elements from start to stop, both included and in range
+func (l *keyStoreList) slice(start, stop int) []any {
+	values := make([]any, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		values = append(values, l.at(i))
+	}
+
+	return values
+}
+
+// pushFront inserts a value at the head of the list
+func (l *keyStoreList) pushFront(value any) {
+	l.grow()
+	l.head = (l.head - 1 + len(l.buf)) % len(l.buf)
+	l.buf[l.head] = value
+	l.n++
+	l.bytes += estimateValue(value)
+}
+
+// pushBack inserts a value at the tail of the list
+func (l *keyStoreList) pushBack(value any) {
+	l.grow()
+	l.buf[(l.head+l.n)%len(l.buf)] = value
+	l.n++
+	l.bytes += estimateValue(value)
+}
+
+// popFront removes and returns the element at the head of the list, which must not be empty
+func (l *keyStoreList) popFront() any {
+	value := l.buf[l.head]
+	l.buf[l.head] = nil
+	l.head = (l.head + 1) % len(l.buf)
+	l.n--
+	l.bytes -= estimateValue(value)
+	l.shrink()
+
+	return value
+}
+
+// popBack removes and returns the element at the tail of the list, which must not be empty
+func (l *keyStoreList) popBack() any {
+	i := (l.head + l.n - 1) % len(l.buf)
+	value := l.buf[i]
+	l.buf[i] = nil
+	l.n--
+	l.bytes -= estimateValue(value)
+	l.shrink()
+
+	return value
+}
+
+// remove removes up to count elements equal to value, see LRem(), and returns how many were removed
+func (l *keyStoreList) remove(count int, value any) int {
+	kept := make([]any, 0, l.n)
+	removed := 0
+	if count >= 0 {
+		for i := 0; i < l.n; i++ {
+			elem := l.at(i)
+			if (count == 0 || removed < count) && reflect.DeepEqual(elem, value) {
+				removed++
+				continue
+			}
+			kept = append(kept, elem)
+		}
+	} else {
+		for i := l.n - 1; i >= 0; i-- {
+			elem := l.at(i)
+			if removed < -count && reflect.DeepEqual(elem, value) {
+				removed++
+				continue
+			}
+			kept = append(kept, elem)
+		}
+		for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+			kept[i], kept[j] = kept[j], kept[i]
+		}
+	}
+
+	if removed > 0 {
+		l.reset(kept)
+	}
+
+	return removed
+}
+
+// reset replaces the elements of the list with values
+func (l *keyStoreList) reset(values []any) {
+	l.buf = values
+	l.head = 0
+	l.n = len(values)
+	l.bytes = 0
+	for _, value := range values {
+		l.bytes += estimateValue(value)
+	}
+	l.shrink()
+}
+
+// grow doubles the ring buffer when it is full
+func (l *keyStoreList) grow() {
+	if l.n < len(l.buf) {
+		return
+	}
+
+	l.resize(max(2*len(l.buf), minListCapacity))
+}
+
+// shrink halves the ring buffer once it is less than a quarter full
+func (l *keyStoreList) shrink() {
+	if len(l.buf) > minListCapacity && l.n < len(l.buf)/4 {
+		l.resize(max(len(l.buf)/2, minListCapacity))
+	}
+}
+
+// resize moves the elements to a ring buffer of the given capacity, starting at its first slot
+func (l *keyStoreList) resize(capacity int) {
+	buf := make([]any, capacity)
+	for i := 0; i < l.n; i++ {
+		buf[i] = l.at(i)
+	}
+
+	l.buf = buf
+	l.head = 0
+}
+
+// add registers the wake channel as waiting on keys
+func (w *keyWaiters) add(wake chan struct{}, keys []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, key := range keys {
+		if w.waiting[key] == nil {
+			w.waiting[key] = make(map[chan struct{}]struct{})
+		}
+		w.waiting[key][wake] = struct{}{}
+	}
+}
+
+// remove unregisters the wake channel from keys
+func (w *keyWaiters) remove(wake chan struct{}, keys []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, key := range keys {
+		delete(w.waiting[key], wake)
+		if len(w.waiting[key]) == 0 {
+			delete(w.waiting, key)
+		}
+	}
+}
+
+// notify wakes up the callers waiting on key. A wake channel holds a single pending
+// wake up, the callers try every key they wait on once woken up.
+func (w *keyWaiters) notify(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for wake := range w.waiting[key] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
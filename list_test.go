@@ -0,0 +1,363 @@
+package fscache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushPop(t *testing.T) {
+	ks := newTestKeyStore()
+
+	n, err := ks.RPush("list", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// values are inserted one after the other, so they end up reversed at the head
+	n, err = ks.LPush("list", "a", "z")
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	values, err := ks.LRange("list", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"z", "a", "b", "c"}, values)
+
+	value, err := ks.LPop("list")
+	require.NoError(t, err)
+	assert.Equal(t, "z", value)
+
+	value, err = ks.RPop("list")
+	require.NoError(t, err)
+	assert.Equal(t, "c", value)
+
+	n, err = ks.LLen("list")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// the key is deleted once the list is empty
+	_, err = ks.LPop("list")
+	require.NoError(t, err)
+	_, err = ks.LPop("list")
+	require.NoError(t, err)
+	_, err = ks.LPop("list")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = ks.Get("list")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestListGrowShrink(t *testing.T) {
+	ks := newTestKeyStore()
+
+	// wrap around the ring buffer from both ends
+	for i := 0; i < 100; i++ {
+		_, err := ks.LPush("list", -i-1)
+		require.NoError(t, err)
+		_, err = ks.RPush("list", i)
+		require.NoError(t, err)
+	}
+
+	values, err := ks.LRange("list", 0, -1)
+	require.NoError(t, err)
+	require.Len(t, values, 200)
+	for i, value := range values {
+		assert.Equal(t, i-100, value)
+	}
+
+	for i := 0; i < 199; i++ {
+		_, err := ks.LPop("list")
+		require.NoError(t, err)
+	}
+
+	value, err := ks.LIndex("list", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 99, value)
+}
+
+func TestLRange(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list", 0, 1, 2, 3, 4)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		start, stop int
+		expected    []any
+	}{
+		"all":             {start: 0, stop: -1, expected: []any{0, 1, 2, 3, 4}},
+		"middle":          {start: 1, stop: 3, expected: []any{1, 2, 3}},
+		"negative":        {start: -2, stop: -1, expected: []any{3, 4}},
+		"stop past end":   {start: 3, stop: 100, expected: []any{3, 4}},
+		"start before":    {start: -100, stop: 0, expected: []any{0}},
+		"start past end":  {start: 5, stop: 10, expected: []any{}},
+		"start past stop": {start: 3, stop: 1, expected: []any{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values, err := ks.LRange("list", tc.start, tc.stop)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+
+	values, err := ks.LRange("missing", 0, -1)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestLIndex(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list", "a", "b", "c")
+	require.NoError(t, err)
+
+	value, err := ks.LIndex("list", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "b", value)
+
+	value, err = ks.LIndex("list", -1)
+	require.NoError(t, err)
+	assert.Equal(t, "c", value)
+
+	_, err = ks.LIndex("list", 3)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+
+	_, err = ks.LIndex("missing", 0)
+	assert.ErrorIs(t, err, ErrIndexOutOfRange)
+}
+
+func TestLTrim(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list", 0, 1, 2, 3, 4)
+	require.NoError(t, err)
+
+	require.NoError(t, ks.LTrim("list", 1, -2))
+	values, err := ks.LRange("list", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []any{1, 2, 3}, values)
+
+	// an empty range deletes the key
+	require.NoError(t, ks.LTrim("list", 5, 10))
+	_, err = ks.Get("list")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	assert.NoError(t, ks.LTrim("missing", 0, 1))
+}
+
+func TestLRem(t *testing.T) {
+	testCases := map[string]struct {
+		count    int
+		removed  int
+		expected []any
+	}{
+		"from head": {count: 2, removed: 2, expected: []any{"b", "b", "a"}},
+		"from tail": {count: -2, removed: 2, expected: []any{"a", "b", "b"}},
+		"all":       {count: 0, removed: 3, expected: []any{"b", "b"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ks := newTestKeyStore()
+			_, err := ks.RPush("list", "a", "b", "a", "b", "a")
+			require.NoError(t, err)
+
+			removed, err := ks.LRem("list", tc.count, "a")
+			require.NoError(t, err)
+			assert.Equal(t, tc.removed, removed)
+
+			values, err := ks.LRange("list", 0, -1)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+
+	ks := newTestKeyStore()
+	removed, err := ks.LRem("missing", 0, "a")
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestListWrongType(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list", 1)
+	require.NoError(t, err)
+
+	_, err = ks.LPush("key1", "value")
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = ks.LPop("key1")
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = ks.LRange("key1", 0, -1)
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = ks.LLen("key2")
+	assert.ErrorIs(t, err, ErrWrongType)
+	_, err = ks.Incr("list")
+	assert.ErrorIs(t, err, ErrWrongType)
+
+	// a list can be replaced by another value
+	require.NoError(t, ks.OverWrite("list", "value"))
+	value, err := ks.Get("list")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestListSnapshot(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list", "a", "b")
+	require.NoError(t, err)
+
+	value, err := ks.Get("list")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a", "b"}, value)
+
+	// the snapshot doesn't change with the list
+	_, err = ks.RPush("list", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a", "b"}, value)
+
+	typ, err := ks.TypeOf("list")
+	require.NoError(t, err)
+	assert.Equal(t, "[]interface {}", typ)
+}
+
+func TestListExpiry(t *testing.T) {
+	ks := newTestKeyStore()
+	clock := newManualClock(time.Now())
+	ks.clock = clock
+	_, err := ks.RPush("list", "a")
+	require.NoError(t, err)
+
+	// pushing keeps the time to live
+	require.NoError(t, ks.Expire("list", time.Minute))
+	_, err = ks.RPush("list", "b")
+	require.NoError(t, err)
+	ttl, err := ks.TTL("list")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	require.NoError(t, ks.ExpireAt("list", clock.Now().Add(time.Millisecond)))
+	clock.Advance(time.Millisecond)
+
+	n, err := ks.LLen("list")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestListSize(t *testing.T) {
+	ks := newTestKeyStore()
+	before := ks.usage.bytes.Load()
+
+	_, err := ks.RPush("list", "a", "b", "c")
+	require.NoError(t, err)
+	assert.Greater(t, ks.usage.bytes.Load(), before)
+
+	_, err = ks.RPop("list")
+	require.NoError(t, err)
+	_, err = ks.RPop("list")
+	require.NoError(t, err)
+	_, err = ks.RPop("list")
+	require.NoError(t, err)
+	assert.Equal(t, before, ks.usage.bytes.Load())
+}
+
+func TestBLPop(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.RPush("list2", "a", "b")
+	require.NoError(t, err)
+
+	// the first non-empty list is popped right away
+	key, value, err := ks.BLPop(context.Background(), "list1", "list2")
+	require.NoError(t, err)
+	assert.Equal(t, "list2", key)
+	assert.Equal(t, "a", value)
+
+	key, value, err = ks.BRPop(context.Background(), "list2")
+	require.NoError(t, err)
+	assert.Equal(t, "list2", key)
+	assert.Equal(t, "b", value)
+
+	// a pop blocks until a push
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		key, value, err := ks.BLPop(context.Background(), "list1", "list2")
+		assert.NoError(t, err)
+		assert.Equal(t, "list1", key)
+		assert.Equal(t, "c", value)
+	}()
+
+	assert.Eventually(t, func() bool {
+		ks.waiters.mu.Lock()
+		defer ks.waiters.mu.Unlock()
+		return len(ks.waiters.waiting["list1"]) == 1
+	}, time.Second, time.Millisecond)
+
+	_, err = ks.RPush("list1", "c")
+	require.NoError(t, err)
+	<-done
+
+	// the waiter is gone
+	assert.Empty(t, ks.waiters.waiting)
+}
+
+func TestBLPopTimeout(t *testing.T) {
+	ks := newTestKeyStore()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := ks.BLPop(ctx, "list")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, _, err = ks.BRPop(context.Background(), "key1")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestBLPopWorkQueue(t *testing.T) {
+	ks := newTestKeyStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const jobs = 1000
+	var (
+		mu       sync.Mutex
+		received = make(map[any]int)
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, value, err := ks.BLPop(ctx, "jobs")
+				if err != nil {
+					return
+				}
+
+				mu.Lock()
+				received[value]++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < jobs; i++ {
+		_, err := ks.RPush("jobs", i)
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == jobs
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	wg.Wait()
+
+	for _, n := range received {
+		assert.Equal(t, 1, n)
+	}
+}
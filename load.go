@@ -0,0 +1,188 @@
+package fscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLoaderPanicked the loader of a GetOrLoad() call panicked
+var ErrLoaderPanicked = errors.New("loader panicked")
+
+type (
+	// Loader loads the value of a key missing from the KeyStore, see GetOrLoad().
+	// It returns the value with its time to live, a zero ttl never expires.
+	Loader func(ctx context.Context) (any, time.Duration, error)
+
+	// loadGroup deduplicates the concurrent loads of the same key and remembers
+	// the failed loads while negative caching is enabled
+	loadGroup struct {
+		mu       sync.Mutex
+		calls    map[string]*loadCall
+		failures map[string]loadFailure
+		// negativeTTL is how long a loader error is cached, zero disables negative caching
+		negativeTTL time.Duration
+	}
+
+	// loadCall is a load in flight, done is closed once value and err are set
+	loadCall struct {
+		done  chan struct{}
+		value any
+		err   error
+	}
+
+	// loadFailure is a cached loader error
+	loadFailure struct {
+		err   error
+		until time.Time
+	}
+)
+
+// newLoadGroup returns a loadGroup with no load in flight and negative caching disabled
+func newLoadGroup() *loadGroup {
+	return &loadGroup{
+		calls:    make(map[string]*loadCall),
+		failures: make(map[string]loadFailure),
+	}
+}
+
+// SetNegativeCaching() makes GetOrLoad() remember a loader error for ttl: until then, loading
+// the key returns the same error without calling the loader again. Errors from a cancelled or
+// timed out context are never cached. A zero ttl disables negative caching, which is the default.
+func (ks *KeyStore) SetNegativeCaching(ttl time.Duration) {
+	ks.loads.mu.Lock()
+	defer ks.loads.mu.Unlock()
+
+	if ttl < 0 {
+		ttl = 0
+	}
+	ks.loads.negativeTTL = ttl
+	if ttl == 0 {
+		clear(ks.loads.failures)
+	}
+}
+
+// GetOrLoad() retrieves a data from the in-memory storage, or loads it with loader and sets it
+// when the key is missing. Concurrent calls for the same key share a single load: the first
+// caller runs the loader with its context, the others wait for its result or for their own
+// context to be done. The loader error is returned as is, and cached if SetNegativeCaching()
+// is enabled.
+//
+//	user, err := ks.GetOrLoad(ctx, "user:1", func(ctx context.Context) (any, time.Duration, error) {
+//		user, err := db.FindUser(ctx, 1)
+//		return user, 5 * time.Minute, err
+//	})
+func (ks *KeyStore) GetOrLoad(ctx context.Context, key string, loader Loader) (any, error) {
+	if value, err := ks.Get(key); err == nil {
+		return value, nil
+	}
+
+	g := ks.loads
+	g.mu.Lock()
+	if failure, ok := g.failures[key]; ok {
+		if ks.clock.Now().Before(failure.until) {
+			g.mu.Unlock()
+			return nil, failure.err
+		}
+		delete(g.failures, key)
+	}
+
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// the key may have been loaded since it was missed
+	if value, err := ks.get(key); err == nil {
+		g.mu.Unlock()
+		return value, nil
+	}
+
+	call := &loadCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	ks.load(ctx, key, loader, call)
+
+	return call.value, call.err
+}
+
+// load runs the loader of a call, stores its result and wakes up the callers waiting for it
+func (ks *KeyStore) load(ctx context.Context, key string, loader Loader, call *loadCall) {
+	g := ks.loads
+
+	// a panicking loader still releases the waiting callers before the panic goes on
+	finished := false
+	defer func() {
+		var recovered any
+		if !finished {
+			recovered = recover()
+			call.err = fmt.Errorf("%w: %v", ErrLoaderPanicked, recovered)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		if call.err != nil && g.negativeTTL > 0 && ctx.Err() == nil {
+			g.failures[key] = loadFailure{err: call.err, until: ks.clock.Now().Add(g.negativeTTL)}
+		}
+		g.mu.Unlock()
+
+		close(call.done)
+
+		if !finished {
+			panic(recovered)
+		}
+	}()
+
+	value, ttl, err := loader(ctx)
+	finished = true
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	call.value = value
+	ks.put(key, value, ttl)
+}
+
+// put sets a data whether the key exists or not
+func (ks *KeyStore) put(key string, value any, ttl time.Duration) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	prev, existed := ks.live(shard, key, now)
+	ks.store(shard, key, KeyStoreData{
+		Value:    value,
+		Duration: expiresAt(now, []time.Duration{ttl}),
+	})
+	if existed {
+		ks.notify(EventOverWrite, key, prev.Value, value)
+		ks.evicted(key, prev.Value, EvictReplaced)
+	} else {
+		ks.notify(EventSet, key, nil, value)
+	}
+	shard.mu.Unlock()
+
+	ks.evict()
+}
+
+// purge drops the cached loader errors which are over
+func (g *loadGroup) purge(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for key, failure := range g.failures {
+		if !now.Before(failure.until) {
+			delete(g.failures, key)
+		}
+	}
+}
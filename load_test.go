@@ -0,0 +1,174 @@
+package fscache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ks := newTestKeyStore()
+	ctx := context.Background()
+
+	var calls int
+	loader := func(context.Context) (any, time.Duration, error) {
+		calls++
+		return "loaded", time.Minute, nil
+	}
+
+	value, err := ks.GetOrLoad(ctx, "key1", loader)
+	require.NoError(t, err)
+	assert.Equal(t, "value1", value)
+	assert.Zero(t, calls)
+
+	value, err = ks.GetOrLoad(ctx, "key4", loader)
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+
+	value, err = ks.GetOrLoad(ctx, "key4", loader)
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+	assert.Equal(t, 1, calls)
+
+	ttl, err := ks.TTL("key4")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+func TestGetOrLoadDeduplicates(t *testing.T) {
+	ks := newTestKeyStore()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(context.Context) (any, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return "loaded", 0, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := ks.GetOrLoad(context.Background(), "key4", loader)
+			assert.NoError(t, err)
+			assert.Equal(t, "loaded", value)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestGetOrLoadWaiterContext(t *testing.T) {
+	ks := newTestKeyStore()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, _ = ks.GetOrLoad(context.Background(), "key4", func(context.Context) (any, time.Duration, error) {
+			close(started)
+			<-release
+			return "loaded", 0, nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := ks.GetOrLoad(ctx, "key4", func(context.Context) (any, time.Duration, error) {
+		t.Error("the load in flight is not shared")
+		return nil, 0, nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	ks := newTestKeyStore()
+	ctx := context.Background()
+	errDB := errors.New("db down")
+
+	var calls int
+	loader := func(context.Context) (any, time.Duration, error) {
+		calls++
+		return nil, 0, errDB
+	}
+
+	// errors are not cached by default
+	_, err := ks.GetOrLoad(ctx, "key4", loader)
+	assert.ErrorIs(t, err, errDB)
+	_, err = ks.GetOrLoad(ctx, "key4", loader)
+	assert.ErrorIs(t, err, errDB)
+	assert.Equal(t, 2, calls)
+
+	_, err = ks.Get("key4")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestGetOrLoadNegativeCaching(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	ks.SetNegativeCaching(time.Minute)
+
+	ctx := context.Background()
+	errDB := errors.New("db down")
+
+	var calls int
+	loader := func(context.Context) (any, time.Duration, error) {
+		calls++
+		if calls == 1 {
+			return nil, 0, errDB
+		}
+		return "loaded", 0, nil
+	}
+
+	_, err := ks.GetOrLoad(ctx, "key4", loader)
+	assert.ErrorIs(t, err, errDB)
+	_, err = ks.GetOrLoad(ctx, "key4", loader)
+	assert.ErrorIs(t, err, errDB)
+	assert.Equal(t, 1, calls)
+
+	ks.clock = fixedClock{now: now.Add(time.Minute)}
+	value, err := ks.GetOrLoad(ctx, "key4", loader)
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+	assert.Equal(t, 2, calls)
+
+	// a cancelled load is not cached
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = ks.GetOrLoad(cancelled, "key5", func(ctx context.Context) (any, time.Duration, error) {
+		return nil, 0, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, ks.loads.failures)
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	ks := newTestKeyStore()
+
+	assert.Panics(t, func() {
+		_, _ = ks.GetOrLoad(context.Background(), "key4", func(context.Context) (any, time.Duration, error) {
+			panic("boom")
+		})
+	})
+
+	// the key can be loaded again
+	value, err := ks.GetOrLoad(context.Background(), "key4", func(context.Context) (any, time.Duration, error) {
+		return "loaded", 0, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+}
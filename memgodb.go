@@ -143,7 +143,7 @@ func (c *Collection) insertOne(obj any) error {
 
 	objMap["colName"] = c.collectionName
 	objMap["id"] = uuid.New()
-	objMap["createdAt"] = time.Now()
+	objMap["createdAt"] = c.dataStore.clock.Now()
 	objMap["updatedAt"] = nil
 	MemgodbStorage = append(MemgodbStorage, objMap)
 
@@ -389,7 +389,7 @@ func (u *Update) One() error {
 					for updateKey, updateValue := range u.update {
 						item[updateKey] = updateValue
 					}
-					item["updatedAt"] = time.Now()
+					item["updatedAt"] = u.collection.dataStore.clock.Now()
 					MemgodbStorage[index] = item
 				}
 			}
@@ -404,8 +404,9 @@ func (u *Update) One() error {
 }
 
 // LoadDefault is used to load data from the JSON file saved on the server using Persist() if any.
+// The file is ./memgodbstorage.json unless another path was set with WithPersistPath().
 func (ds *DataStore) LoadDefault() error {
-	f, err := os.Open("./memgodbstorage.json")
+	f, err := os.Open(ds.persistFile())
 	if err != nil {
 		return errors.New("error finding file")
 	}
@@ -454,10 +455,13 @@ func (ds *DataStore) LoadDefault() error {
 // Persist is used to write data to file. All data will be saved into a JSON file on the server.
 
 // This method will make sure all your data are saved into a JSON file. A cron job runs ever minute and writes your data into a JSON file to ensure data integrity
-func (ds *DataStore) Persist() error {
+func (ds *DataStore) Persist() (err error) {
 	if MemgodbStorage == nil {
 		return nil
 	}
+	defer func(start time.Time) {
+		ds.observers.persist(start, err)
+	}(time.Now())
 
 	persistDataStoreData = true
 	jsonByte, err := json.Marshal(MemgodbStorage)
@@ -465,7 +469,7 @@ func (ds *DataStore) Persist() error {
 		return err
 	}
 
-	file, err := os.Create("./memgodbstorage.json")
+	file, err := os.Create(ds.persistFile())
 	if err != nil {
 		return err
 	}
@@ -479,6 +483,15 @@ func (ds *DataStore) Persist() error {
 	return nil
 }
 
+// persistFile returns the path of the JSON file data is persisted into
+func (ds *DataStore) persistFile() string {
+	if ds.persistPath == "" {
+		return defaultPersistPath
+	}
+
+	return ds.persistPath
+}
+
 // decode decodes an any into a map[string]any
 func (*Collection) decode(obj any) (map[string]any, error) {
 	objMap := make(map[string]any)
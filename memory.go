@@ -0,0 +1,246 @@
+package fscache
+
+import (
+	"reflect"
+	"sync/atomic"
+	"unsafe"
+)
+
+type (
+	// Sizer is implemented by the values which know how many bytes they occupy in memory.
+	// The ReflectSizeEstimator uses it instead of walking them.
+	Sizer interface {
+		MemorySize() int64
+	}
+
+	// SizeEstimator estimates how many bytes a value occupies in memory, see SetSizeEstimator()
+	SizeEstimator interface {
+		EstimateSize(value any) int64
+	}
+
+	// ReflectSizeEstimator is the default SizeEstimator. It walks the value with reflection,
+	// following pointers, slices and maps, counts every pointer target and the data shared
+	// by strings only once and asks the values implementing Sizer for their size.
+	ReflectSizeEstimator struct{}
+
+	// sizeEstimator holds the SizeEstimator in use, it can be swapped atomically
+	sizeEstimator struct {
+		holder atomic.Pointer[estimatorHolder]
+	}
+
+	// estimatorHolder wraps a SizeEstimator so it can be swapped atomically
+	estimatorHolder struct {
+		SizeEstimator
+	}
+)
+
+// sizerType is the reflection type of the Sizer interface
+var sizerType = reflect.TypeFor[Sizer]()
+
+// EstimateSize() returns an estimate of the number of bytes value occupies in memory
+func (ReflectSizeEstimator) EstimateSize(value any) int64 {
+	return estimateValue(value) - int64(unsafe.Sizeof(value))
+}
+
+// SetSizeEstimator() sets how the size of the KeyStore values and DataStore documents is
+// estimated, ReflectSizeEstimator by default. The data objects already stored keep the
+// size they were estimated at. The values of lists, hashes, sets and sorted sets are
+// always estimated with ReflectSizeEstimator.
+func (ks *KeyStore) SetSizeEstimator(estimator SizeEstimator) {
+	if estimator == nil {
+		estimator = ReflectSizeEstimator{}
+	}
+
+	ks.sizes.holder.Store(&estimatorHolder{estimator})
+}
+
+// MemoryUsage() returns the estimated number of bytes held by the KeyStore data objects,
+// keys and bookkeeping included. Expired data objects are counted until they get reclaimed.
+func (ks *KeyStore) MemoryUsage() int64 {
+	return ks.usage.bytes.Load()
+}
+
+// KeyMemoryUsage() returns the estimated number of bytes held by a data object and its key,
+// like the Redis MEMORY USAGE command
+func (ks *KeyStore) KeyMemoryUsage(key string) (int64, error) {
+	shard := ks.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	data, ok := shard.items[key]
+	if !ok || data.expired(ks.clock.Now()) {
+		return 0, ErrKeyNotFound
+	}
+
+	return data.size, nil
+}
+
+// MemoryUsage() returns the estimated number of bytes held by the documents of every namespace
+func (ds *DataStore) MemoryUsage() map[string]int64 {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	usage := make(map[string]int64, len(ds.schemas))
+	for namespace := range ds.schemas {
+		usage[namespace] = ds.namespaceSize(namespace)
+	}
+
+	return usage
+}
+
+// MemoryUsage() returns the estimated number of bytes held by the documents of the namespace
+func (ns *Namespace) MemoryUsage() int64 {
+	ds := ns.dataStore
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	return ds.namespaceSize(ns.namespace)
+}
+
+// namespaceSize estimates the bytes held by the documents of a namespace.
+// The caller must hold the DataStore lock.
+func (ds *DataStore) namespaceSize(namespace string) int64 {
+	docs := ds.data[namespace]
+	size := int64(cap(docs)) * int64(unsafe.Sizeof(map[string]any{}))
+	for _, doc := range docs {
+		size += ds.sizes.estimate(doc)
+	}
+
+	return size
+}
+
+// estimateSize returns an estimate of the number of bytes an entry occupies in memory:
+// the data object, its key and its value estimated with the SizeEstimator in use.
+// Collections report their own size.
+func (ks *KeyStore) estimateSize(key string, value any) int64 {
+	size := int64(unsafe.Sizeof(KeyStoreData{})) + int64(len(key))
+	if c, ok := value.(collection); ok {
+		return size + c.memSize()
+	}
+
+	return size + ks.sizes.estimate(value)
+}
+
+// estimate returns the size of value estimated with the SizeEstimator in use.
+// A nil sizeEstimator uses ReflectSizeEstimator.
+func (s *sizeEstimator) estimate(value any) int64 {
+	if s == nil {
+		return ReflectSizeEstimator{}.EstimateSize(value)
+	}
+
+	if holder := s.holder.Load(); holder != nil {
+		return holder.EstimateSize(value)
+	}
+
+	return ReflectSizeEstimator{}.EstimateSize(value)
+}
+
+// estimateValue returns an estimate of the number of bytes a value held in an interface
+// occupies in memory, the interface included
+func estimateValue(value any) int64 {
+	size := int64(unsafe.Sizeof(value))
+	if value == nil {
+		return size
+	}
+
+	v := reflect.ValueOf(value)
+	if sizer, ok := sizerOf(v); ok {
+		return size + sizer.MemorySize()
+	}
+
+	return size + int64(v.Type().Size()) + sizeOfReferences(v, make(map[uintptr]int64))
+}
+
+// sizerOf returns the value as a Sizer if it implements it and is not a nil pointer
+func sizerOf(v reflect.Value) (Sizer, bool) {
+	if !v.Type().Implements(sizerType) || !v.CanInterface() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return nil, false
+	}
+
+	return v.Interface().(Sizer), true
+}
+
+// stringSize returns the estimated bytes held by a string, its header included
+func stringSize(s string) int64 {
+	return int64(unsafe.Sizeof(s)) + int64(len(s))
+}
+
+// sizeOfReferences returns the bytes held outside of the value itself,
+// i.e. the memory reached through strings, pointers, slices, maps and interfaces.
+// The memory reached more than once is counted once, seen holds its addresses along with
+// the bytes of string data counted at each of them.
+func sizeOfReferences(v reflect.Value, seen map[uintptr]int64) int64 {
+	if sizer, ok := sizerOf(v); ok {
+		return max(sizer.MemorySize()-int64(v.Type().Size()), 0)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if v.Len() == 0 {
+			return 0
+		}
+		// a string sharing the data of another one, like a substring, only adds what is longer
+		addr := uintptr(unsafe.Pointer(unsafe.StringData(v.String())))
+		counted := seen[addr]
+		if int64(v.Len()) <= counted {
+			return 0
+		}
+		seen[addr] = int64(v.Len())
+		return int64(v.Len()) - counted
+	case reflect.Pointer:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		elem := v.Elem()
+		return int64(elem.Type().Size()) + sizeOfReferences(elem, seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		elem := v.Elem()
+		return int64(elem.Type().Size()) + sizeOfReferences(elem, seen)
+	case reflect.Slice:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOfReferences(v.Index(i), seen)
+		}
+		return size
+	case reflect.Array:
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOfReferences(v.Index(i), seen)
+		}
+		return size
+	case reflect.Map:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		size := int64(v.Len()) * int64(v.Type().Key().Size()+v.Type().Elem().Size())
+		iter := v.MapRange()
+		for iter.Next() {
+			size += sizeOfReferences(iter.Key(), seen) + sizeOfReferences(iter.Value(), seen)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += sizeOfReferences(v.Field(i), seen)
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
+// visited reports whether the address was already counted, and marks it as counted
+func visited(addr uintptr, seen map[uintptr]int64) bool {
+	if _, ok := seen[addr]; ok {
+		return true
+	}
+
+	seen[addr] = 0
+	return false
+}
@@ -0,0 +1,106 @@
+package fscache
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blob is a value reporting its own size
+type blob struct {
+	data []byte
+}
+
+func (b *blob) MemorySize() int64 {
+	return 1 << 20
+}
+
+// fixedEstimator estimates every value at the same size
+type fixedEstimator int64
+
+func (e fixedEstimator) EstimateSize(any) int64 {
+	return int64(e)
+}
+
+func TestReflectSizeEstimator(t *testing.T) {
+	estimator := ReflectSizeEstimator{}
+
+	assert.EqualValues(t, 8, estimator.EstimateSize(int64(1)))
+	assert.EqualValues(t, int64(unsafe.Sizeof(""))+100, estimator.EstimateSize(strings.Repeat("a", 100)))
+
+	// the data shared by strings is counted once
+	s := strings.Repeat("a", 100)
+	header := int64(unsafe.Sizeof(s))
+	assert.Equal(t, 2*header+100, estimator.EstimateSize(struct{ A, B string }{A: s, B: s}))
+	assert.Equal(t, 2*header+200, estimator.EstimateSize(struct{ A, B string }{A: s, B: strings.Repeat("a", 100)}))
+	slice := int64(unsafe.Sizeof([]string{}))
+	assert.Equal(t, slice+2*header+100, estimator.EstimateSize([]string{s, s}))
+	assert.Equal(t, slice+2*header+100, estimator.EstimateSize([]string{s[:50], s}))
+
+	// so are the values shared through pointers
+	p := &[100]byte{}
+	assert.Equal(t, 2*int64(unsafe.Sizeof(any(nil)))+2*int64(unsafe.Sizeof(p))+100, estimator.EstimateSize(struct{ A, B any }{A: p, B: p}))
+
+	// Sizer values report their own size, even nested
+	assert.EqualValues(t, 1<<20, estimator.EstimateSize(&blob{}))
+	nested := estimator.EstimateSize(struct{ B *blob }{B: &blob{}})
+	assert.GreaterOrEqual(t, nested, int64(1<<20))
+	assert.Less(t, nested, int64(1<<20+64))
+	assert.EqualValues(t, 8, estimator.EstimateSize((*blob)(nil)))
+}
+
+func TestKeyStoreMemoryUsage(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	assert.Zero(t, ks.MemoryUsage())
+
+	require.NoError(t, ks.Set("small", "a"))
+	require.NoError(t, ks.Set("large", strings.Repeat("a", 10000)))
+
+	small, err := ks.KeyMemoryUsage("small")
+	require.NoError(t, err)
+	large, err := ks.KeyMemoryUsage("large")
+	require.NoError(t, err)
+	assert.Greater(t, large-small, int64(9000))
+	assert.Equal(t, small+large, ks.MemoryUsage())
+
+	_, err = ks.KeyMemoryUsage("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, ks.Del("large"))
+	assert.Equal(t, small, ks.MemoryUsage())
+
+	// a custom estimator applies to the data objects stored afterwards
+	ks.SetSizeEstimator(fixedEstimator(1000))
+	require.NoError(t, ks.Set("custom", "a"))
+	custom, err := ks.KeyMemoryUsage("custom")
+	require.NoError(t, err)
+	assert.Equal(t, int64(unsafe.Sizeof(KeyStoreData{}))+int64(len("custom"))+1000, custom)
+	assert.Equal(t, small+custom, ks.MemoryUsage())
+}
+
+func TestDataStoreMemoryUsage(t *testing.T) {
+	fs := New(WithSizeEstimator(fixedEstimator(100)))
+	ds := fs.DataStore()
+
+	users := ds.Namespace("user")
+	orders := ds.Namespace("order")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, users.Create(map[string]any{"name": "jane"}))
+	}
+
+	usage := ds.MemoryUsage()
+	assert.Greater(t, usage["users"], int64(300))
+	assert.Zero(t, usage["orders"])
+	assert.Equal(t, usage["users"], users.MemoryUsage())
+	assert.Zero(t, orders.MemoryUsage())
+
+	// the estimator is shared with the KeyStore
+	require.NoError(t, fs.KeyStore().Set("key", "value"))
+	size, err := fs.KeyStore().KeyMemoryUsage("key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(unsafe.Sizeof(KeyStoreData{}))+int64(len("key"))+100, size)
+}
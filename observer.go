@@ -0,0 +1,97 @@
+package fscache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// SyncSQL the documents were synced to a SQL database with ConnectSQLDB.Sync()
+	SyncSQL SyncDatabase = "sql"
+	// SyncMongoDB the documents were synced to a MongoDB database with ConnectMongoDB.Sync()
+	SyncMongoDB SyncDatabase = "mongodb"
+)
+
+type (
+	// SyncDatabase tells which kind of database documents were synced to, see Observer
+	SyncDatabase string
+
+	// Observer is told about the DataStore work the statistics don't cover, to export it as
+	// metrics for instance, see Observe(). The methods are called from the goroutine doing the
+	// work, some while the DataStore is locked, so they must be quick and not use the cache.
+	// The durations are measured with the system clock, whatever the clock of the cache.
+	Observer interface {
+		// ObserveQuery() is called with the namespace and the duration of every query, by
+		// Query(), Find() and First()
+		ObserveQuery(namespace string, duration time.Duration)
+		// ObserveSync() is called for every document a Sync() worker tried to sync, err is
+		// the error of the database if it failed
+		ObserveSync(database SyncDatabase, namespace string, err error)
+		// ObservePersist() is called with the duration and the error of every Persist()
+		// writing the DataStore data
+		ObservePersist(duration time.Duration, err error)
+	}
+
+	// observers holds the registered observers. Registering copies the list, so telling
+	// the observers takes no lock.
+	observers struct {
+		mu   sync.Mutex
+		list atomic.Pointer[[]Observer]
+	}
+)
+
+// Observe() registers an observer of the DataStore queries, syncs and persistence,
+// see Observer
+func (c *Cache) Observe(observer Observer) {
+	c.DataStoreInstance.observers.add(observer)
+}
+
+// add registers an observer
+func (o *observers) add(observer Observer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var list []Observer
+	if current := o.list.Load(); current != nil {
+		list = append(list, *current...)
+	}
+	list = append(list, observer)
+
+	o.list.Store(&list)
+}
+
+// each calls fn with every registered observer
+func (o *observers) each(fn func(Observer)) {
+	list := o.list.Load()
+	if list == nil {
+		return
+	}
+
+	for _, observer := range *list {
+		fn(observer)
+	}
+}
+
+// query tells the observers about a query of namespace which started at start
+func (o *observers) query(namespace string, start time.Time) {
+	duration := time.Since(start)
+	o.each(func(observer Observer) {
+		observer.ObserveQuery(namespace, duration)
+	})
+}
+
+// sync tells the observers about a document synced to database
+func (o *observers) sync(database SyncDatabase, namespace string, err error) {
+	o.each(func(observer Observer) {
+		observer.ObserveSync(database, namespace, err)
+	})
+}
+
+// persist tells the observers about a Persist() which started at start
+func (o *observers) persist(start time.Time, err error) {
+	duration := time.Since(start)
+	o.each(func(observer Observer) {
+		observer.ObservePersist(duration, err)
+	})
+}
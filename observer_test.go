@@ -0,0 +1,75 @@
+package fscache
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver records what it is told about
+type recordingObserver struct {
+	mu       sync.Mutex
+	queries  []string
+	syncs    []error
+	persists []error
+}
+
+func (o *recordingObserver) ObserveQuery(namespace string, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.queries = append(o.queries, namespace)
+}
+
+func (o *recordingObserver) ObserveSync(_ SyncDatabase, _ string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.syncs = append(o.syncs, err)
+}
+
+func (o *recordingObserver) ObservePersist(_ time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.persists = append(o.persists, err)
+}
+
+func TestObserveQuery(t *testing.T) {
+	fs := New()
+	first, second := &recordingObserver{}, &recordingObserver{}
+	fs.Observe(first)
+
+	users := fs.DataStore().Namespace("user")
+	require.NoError(t, users.Create(map[string]any{"name": "jane"}))
+	_, err := users.Query(map[string]any{"name": "jane"})
+	require.NoError(t, err)
+
+	fs.Observe(second)
+	var found []map[string]any
+	require.NoError(t, users.Find(nil, &found))
+	// the lookups of Update() and Delete() are not queries
+	require.NoError(t, users.Update(map[string]any{"name": "jane"}, map[string]any{"age": 30}))
+	require.NoError(t, users.Delete(map[string]any{"name": "jane"}))
+
+	assert.Equal(t, []string{"users", "users"}, first.queries)
+	assert.Equal(t, []string{"users"}, second.queries)
+}
+
+func TestObservePersist(t *testing.T) {
+	observer := &recordingObserver{}
+
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	fs.Observe(observer)
+	require.NoError(t, fs.DataStore().Collection("user").Insert(map[string]any{"name": "Jane Doe"}))
+	require.NoError(t, fs.DataStore().Persist())
+
+	fs = New(WithPersistPath(filepath.Join(t.TempDir(), "missing", "storage.json")))
+	fs.Observe(observer)
+	assert.Error(t, fs.DataStore().Persist())
+
+	require.Len(t, observer.persists, 2)
+	assert.NoError(t, observer.persists[0])
+	assert.Error(t, observer.persists[1])
+}
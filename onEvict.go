@@ -0,0 +1,131 @@
+package fscache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// EvictExpired the data object expired
+	EvictExpired EvictReason = "expired"
+	// EvictEvicted the data object was evicted to bring the KeyStore back under its capacity
+	EvictEvicted EvictReason = "evicted"
+	// EvictDeleted the data object was deleted, by Del() or InvalidateTag() for instance
+	EvictDeleted EvictReason = "deleted"
+	// EvictReplaced the value of the data object was replaced by another one
+	EvictReplaced EvictReason = "replaced"
+	// EvictCleared the data object was deleted by Clear()
+	EvictCleared EvictReason = "cleared"
+)
+
+type (
+	// EvictReason tells why a value left the KeyStore, see OnEvict()
+	EvictReason string
+
+	// EvictFunc is called with the key and the value that left the KeyStore, see OnEvict()
+	EvictFunc func(key string, value any, reason EvictReason)
+
+	// evictHooks queues the values leaving the KeyStore and runs the OnEvict() callbacks
+	// with them from a single goroutine, so that the callbacks run in order and outside
+	// the locks of the KeyStore
+	evictHooks struct {
+		mu        sync.Mutex
+		callbacks []EvictFunc
+		queue     []eviction
+		// wake signals the dispatcher that the queue is not empty
+		wake chan struct{}
+		// count is the number of callbacks, it saves queueing values nobody is told about
+		count   atomic.Int64
+		started bool
+		stopped bool
+	}
+
+	// eviction is a value which left the KeyStore
+	eviction struct {
+		key    string
+		value  any
+		reason EvictReason
+	}
+)
+
+// newEvictHooks returns evictHooks without callbacks
+func newEvictHooks() *evictHooks {
+	return &evictHooks{
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// OnEvict() registers a callback called whenever a value leaves the KeyStore: it expired,
+// got evicted, deleted, replaced by another value or cleared. Callbacks are called one at a
+// time, in the order the values left, from a goroutine of their own once the KeyStore locks
+// are released, so they can use the KeyStore. A slow callback delays the following ones.
+// Once the cache is closed, the queued values are handed to the callbacks and the values
+// leaving afterwards are not.
+func (ks *KeyStore) OnEvict(fn EvictFunc) {
+	h := ks.evictions
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.callbacks = append(h.callbacks, fn)
+	h.count.Add(1)
+
+	if !h.started {
+		h.started = true
+		if !ks.lifecycle.goroutine(h.dispatch) {
+			h.stopped = true
+		}
+	}
+}
+
+// evicted queues a value leaving the KeyStore for the OnEvict() callbacks
+func (ks *KeyStore) evicted(key string, value any, reason EvictReason) {
+	h := ks.evictions
+	if h.count.Load() == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return
+	}
+	h.queue = append(h.queue, eviction{key: key, value: value, reason: reason})
+	h.mu.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch runs the callbacks with the queued values until done is closed
+func (h *evictHooks) dispatch(done <-chan struct{}) {
+	for {
+		select {
+		case <-h.wake:
+			h.flush()
+		case <-done:
+			h.mu.Lock()
+			h.stopped = true
+			h.mu.Unlock()
+
+			h.flush()
+			return
+		}
+	}
+}
+
+// flush runs the callbacks with the values queued so far
+func (h *evictHooks) flush() {
+	h.mu.Lock()
+	queue, callbacks := h.queue, h.callbacks
+	h.queue = nil
+	h.mu.Unlock()
+
+	for _, e := range queue {
+		value := exposed(e.value)
+		for _, fn := range callbacks {
+			fn(e.key, value, e.reason)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package fscache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evictRecorder records the OnEvict() callback calls
+type evictRecorder struct {
+	mu    sync.Mutex
+	calls []eviction
+}
+
+func (r *evictRecorder) record(key string, value any, reason EvictReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, eviction{key: key, value: value, reason: reason})
+}
+
+// wait returns the calls once there are n of them
+func (r *evictRecorder) wait(t *testing.T, n int) []eviction {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.calls) >= n
+	}, time.Second, time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]eviction(nil), r.calls...)
+}
+
+func TestOnEvict(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	recorder := &evictRecorder{}
+	ks.OnEvict(recorder.record)
+
+	require.NoError(t, ks.OverWrite("key2", 20))
+	require.NoError(t, ks.Del("key3"))
+	require.NoError(t, ks.Set("session", "token", time.Minute))
+	_, err := ks.RPush("list", "a", "b")
+	require.NoError(t, err)
+
+	ks.clock = fixedClock{now: now.Add(time.Minute)}
+	_, err = ks.Get("session")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, ks.Clear())
+
+	calls := recorder.wait(t, 6)
+	assert.Equal(t, []eviction{
+		{key: "key2", value: 10, reason: EvictReplaced},
+		{key: "key3", value: true, reason: EvictDeleted},
+		{key: "session", value: "token", reason: EvictExpired},
+	}, calls[:3])
+	assert.ElementsMatch(t, []eviction{
+		{key: "key1", value: "value1", reason: EvictCleared},
+		{key: "key2", value: 20, reason: EvictCleared},
+		{key: "list", value: []any{"a", "b"}, reason: EvictCleared},
+	}, calls[3:])
+}
+
+func TestOnEvictCapacity(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	ks.SetCapacity(1, 0)
+
+	recorder := &evictRecorder{}
+	ks.OnEvict(recorder.record)
+
+	require.NoError(t, ks.Set("key1", "value1"))
+	require.NoError(t, ks.Set("key2", "value2"))
+
+	assert.Equal(t, []eviction{{key: "key1", value: "value1", reason: EvictEvicted}}, recorder.wait(t, 1))
+}
+
+func TestOnEvictCallsBack(t *testing.T) {
+	ks := newTestKeyStore()
+
+	// the callback runs outside the KeyStore locks, it can use the KeyStore
+	moved := make(chan struct{})
+	ks.OnEvict(func(key string, value any, reason EvictReason) {
+		if reason == EvictDeleted {
+			assert.NoError(t, ks.Set("archive:"+key, value))
+			close(moved)
+		}
+	})
+
+	require.NoError(t, ks.Del("key1"))
+	<-moved
+
+	value, err := ks.Get("archive:key1")
+	require.NoError(t, err)
+	assert.Equal(t, "value1", value)
+}
+
+func TestOnEvictStopped(t *testing.T) {
+	ks := newTestKeyStore()
+
+	recorder := &evictRecorder{}
+	ks.OnEvict(recorder.record)
+	require.NoError(t, ks.Del("key1"))
+
+	// the queued values are flushed when the lifecycle stops, the later ones are dropped
+	ks.lifecycle.stop()
+	ks.lifecycle.wg.Wait()
+	require.NoError(t, ks.Del("key2"))
+
+	assert.Equal(t, []eviction{{key: "key1", value: "value1", reason: EvictDeleted}}, recorder.wait(t, 1))
+	assert.Empty(t, ks.evictions.queue)
+}
@@ -0,0 +1,158 @@
+package fscache
+
+import (
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// defaultCleanupInterval is how often the runner performs its maintenance tasks by default
+	defaultCleanupInterval = 30 * time.Second
+	// defaultPersistPath is the file the DataStore data is persisted into by default
+	defaultPersistPath = "./memgodbstorage.json"
+)
+
+type (
+	// Option configures the cache created with New()
+	Option func(*config)
+
+	// config holds the settings of a cache
+	config struct {
+		cleanupInterval      time.Duration
+		logger               zerolog.Logger
+		clock                Clock
+		maxEntries           int
+		maxBytes             int64
+		evictionPolicy       EvictionPolicy
+		persistPath          string
+		activeExpiryInterval time.Duration
+		activeExpirySamples  int
+		pubSubBufferSize     int
+		slowConsumerPolicy   SlowConsumerPolicy
+		negativeTTL          time.Duration
+		ttlJitter            TTLJitter
+		sizeEstimator        SizeEstimator
+	}
+)
+
+// defaultConfig returns the settings used when New() is called without options
+func defaultConfig() config {
+	return config{
+		cleanupInterval:      defaultCleanupInterval,
+		logger:               zerolog.New(io.Discard),
+		clock:                systemClock{},
+		persistPath:          defaultPersistPath,
+		activeExpiryInterval: defaultActiveExpiryInterval,
+		activeExpirySamples:  defaultActiveExpirySamples,
+		pubSubBufferSize:     defaultPubSubBufferSize,
+		slowConsumerPolicy:   SlowConsumerDrop,
+	}
+}
+
+// WithCleanupInterval sets how often the runner performs its maintenance tasks,
+// such as persisting the DataStore data. It defaults to 30 seconds.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.cleanupInterval = interval
+		}
+	}
+}
+
+// WithLogger sets the logger of the cache, logs are discarded by default
+func WithLogger(logger zerolog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithClock sets the clock the cache tells the time with, the system clock by default
+func WithClock(clock Clock) Option {
+	return func(c *config) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// WithCapacity bounds the KeyStore to maxEntries entries and maxBytes estimated bytes,
+// see KeyStore.SetCapacity(). The KeyStore is unbounded by default.
+//
+// A bounded KeyStore tells its eviction policy about every read and write, and the built-in
+// policies serialize these calls behind a single lock. Reads of different keys then contend
+// on that lock even though they lock different shards, the price of evicting keys by
+// recency or frequency.
+func WithCapacity(maxEntries int, maxBytes int64) Option {
+	return func(c *config) {
+		c.maxEntries = maxEntries
+		c.maxBytes = maxBytes
+	}
+}
+
+// WithEvictionPolicy sets the policy picking the keys to evict once the KeyStore is
+// over capacity, see KeyStore.SetEvictionPolicy(). It defaults to LRU.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(c *config) {
+		c.evictionPolicy = policy
+	}
+}
+
+// WithPersistPath sets the JSON file the DataStore data is persisted into and loaded from.
+// It defaults to ./memgodbstorage.json.
+func WithPersistPath(path string) Option {
+	return func(c *config) {
+		if path != "" {
+			c.persistPath = path
+		}
+	}
+}
+
+// WithActiveExpiry sets the rate of the KeyStore active expiry cycle,
+// see KeyStore.SetActiveExpiry(). It defaults to 20 samples every 100ms.
+func WithActiveExpiry(interval time.Duration, samples int) Option {
+	return func(c *config) {
+		if interval > 0 {
+			c.activeExpiryInterval = interval
+		}
+		if samples > 0 {
+			c.activeExpirySamples = samples
+		}
+	}
+}
+
+// WithPubSub sets the number of messages buffered per Pub/Sub subscriber and what Publish()
+// does once the buffer of a subscriber is full. It defaults to 64 messages and SlowConsumerDrop.
+func WithPubSub(bufferSize int, policy SlowConsumerPolicy) Option {
+	return func(c *config) {
+		if bufferSize >= 0 {
+			c.pubSubBufferSize = bufferSize
+		}
+		c.slowConsumerPolicy = policy
+	}
+}
+
+// WithNegativeCaching makes KeyStore.GetOrLoad() cache the loader errors for ttl,
+// see KeyStore.SetNegativeCaching(). Loader errors are not cached by default.
+func WithNegativeCaching(ttl time.Duration) Option {
+	return func(c *config) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithTTLJitter spreads the time to live of the KeyStore Set(), SetMany() and OverWrite()
+// calls, see KeyStore.SetTTLJitter(). There is no jitter by default.
+func WithTTLJitter(jitter TTLJitter) Option {
+	return func(c *config) {
+		c.ttlJitter = jitter
+	}
+}
+
+// WithSizeEstimator sets how the size of the KeyStore values and DataStore documents is
+// estimated, see KeyStore.SetSizeEstimator(). It defaults to ReflectSizeEstimator.
+func WithSizeEstimator(estimator SizeEstimator) Option {
+	return func(c *config) {
+		c.sizeEstimator = estimator
+	}
+}
@@ -0,0 +1,109 @@
+package fscache
+
+// matchPattern reports whether s matches the Redis glob-style pattern: a star matches any
+// sequence of characters, a question mark any single character, [abc] one of the characters
+// between brackets and [^abc] or [!abc] any other, [a-z] a character in the range, and a
+// backslash escapes the next character, e.g. \* matches a star.
+// A malformed pattern, like an unterminated bracket, is matched as best as possible
+// rather than rejected, as Redis does.
+func matchPattern(pattern, s string) bool {
+	// star and starS remember the last star seen and where s stood then,
+	// so a mismatch can backtrack and let the star swallow one more byte
+	star, starS := -1, 0
+	p, i := 0, 0
+
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, starS = p, i
+				p++
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if next, ok := matchClass(pattern, p, s[i]); ok {
+					p = next
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) {
+					if pattern[p+1] == s[i] {
+						p += 2
+						i++
+						continue
+					}
+					break
+				}
+				fallthrough
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+
+		if star < 0 {
+			return false
+		}
+
+		starS++
+		p, i = star+1, starS
+	}
+
+	// trailing stars match the empty remainder
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// matchClass matches c against the bracket class starting at pattern[p]. It returns the
+// index following the class and whether c belongs to it.
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	p++
+	negate := p < len(pattern) && (pattern[p] == '^' || pattern[p] == '!')
+	if negate {
+		p++
+	}
+
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		lo := pattern[p]
+		if lo == '\\' && p+1 < len(pattern) {
+			p++
+			lo = pattern[p]
+		}
+		p++
+
+		hi := lo
+		if p+1 < len(pattern) && pattern[p] == '-' && pattern[p+1] != ']' {
+			hi = pattern[p+1]
+			if hi == '\\' && p+2 < len(pattern) {
+				p++
+				hi = pattern[p+1]
+			}
+			p += 2
+		}
+
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+
+	// skip the closing bracket, an unterminated class ends with the pattern
+	if p < len(pattern) {
+		p++
+	}
+
+	return p, matched != negate
+}
@@ -0,0 +1,45 @@
+package fscache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{pattern: "*", s: "", match: true},
+		{pattern: "*", s: "anything", match: true},
+		{pattern: "user:*", s: "user:1", match: true},
+		{pattern: "user:*", s: "users:1", match: false},
+		{pattern: "*:name", s: "user:1:name", match: true},
+		{pattern: "a*b*c", s: "aXXbYYc", match: true},
+		{pattern: "a*b*c", s: "aXXbYY", match: false},
+		{pattern: "h?llo", s: "hello", match: true},
+		{pattern: "h?llo", s: "hllo", match: false},
+		{pattern: "h[ae]llo", s: "hallo", match: true},
+		{pattern: "h[ae]llo", s: "hillo", match: false},
+		{pattern: "h[^e]llo", s: "hallo", match: true},
+		{pattern: "h[^e]llo", s: "hello", match: false},
+		{pattern: "h[!e]llo", s: "hello", match: false},
+		{pattern: "h[a-c]llo", s: "hbllo", match: true},
+		{pattern: "h[a-c]llo", s: "hdllo", match: false},
+		{pattern: "h[c-a]llo", s: "hbllo", match: true},
+		{pattern: `a\*b`, s: "a*b", match: true},
+		{pattern: `a\*b`, s: "aXb", match: false},
+		{pattern: `a[\]]b`, s: "a]b", match: true},
+		{pattern: `a\`, s: `a\`, match: true},
+		{pattern: "a[bc", s: "ab", match: true},
+		{pattern: "exact", s: "exact", match: true},
+		{pattern: "exact", s: "exactly", match: false},
+		{pattern: "**", s: "x", match: true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.match, matchPattern(tc.pattern, tc.s), "pattern %q on %q", tc.pattern, tc.s)
+	}
+}
@@ -0,0 +1,256 @@
+// Package prometheus exports the statistics of fs-cache as Prometheus metrics. It is a
+// module of its own, so that the core module doesn't depend on the Prometheus client.
+//
+//	import (
+//		fscache "github.com/jiyamathias/fs-cache"
+//		fscacheprom "github.com/jiyamathias/fs-cache/prometheus"
+//		"github.com/prometheus/client_golang/prometheus"
+//	)
+//
+//	fs := fscache.New()
+//	prometheus.MustRegister(fscacheprom.NewCollector(fs))
+package prometheus
+
+import (
+	"time"
+
+	fscache "github.com/jiyamathias/fs-cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// metricNamespace prefixes the names of the metrics
+	metricNamespace = "fscache"
+
+	// resultSuccess and resultFailure are the values of the result label
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+type (
+	// Option configures the Collector created with NewCollector()
+	Option func(*config)
+
+	// config holds the settings of a Collector
+	config struct {
+		constLabels    prometheus.Labels
+		queryBuckets   []float64
+		persistBuckets []float64
+	}
+
+	// Collector is a prometheus.Collector exporting the statistics of a cache, see
+	// fscache.Stats, and the duration of its queries, its syncs and its persistence.
+	// The counters restart from zero when the statistics are reset with ResetStats(),
+	// which Prometheus handles as a counter reset.
+	Collector struct {
+		cache fscache.Operations
+
+		hits        *prometheus.Desc
+		misses      *prometheus.Desc
+		hitRatio    *prometheus.Desc
+		sets        *prometheus.Desc
+		deletes     *prometheus.Desc
+		expirations *prometheus.Desc
+		evictions   *prometheus.Desc
+		entries     *prometheus.Desc
+		bytes       *prometheus.Desc
+		documents   *prometheus.Desc
+		docBytes    *prometheus.Desc
+		queries     *prometheus.Desc
+
+		observer *observer
+	}
+
+	// observer records what the cache tells its observers, see fscache.Observer
+	observer struct {
+		queryDuration   *prometheus.HistogramVec
+		syncs           *prometheus.CounterVec
+		persistDuration prometheus.Histogram
+		persistFailures prometheus.Counter
+	}
+)
+
+// WithConstLabels sets labels added to every metric, to tell several caches apart
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) {
+		c.constLabels = labels
+	}
+}
+
+// WithQueryBuckets sets the buckets of the query duration histogram, in seconds.
+// They default to 10µs to 2.6s, every bucket 4 times the previous one.
+func WithQueryBuckets(buckets []float64) Option {
+	return func(c *config) {
+		if len(buckets) > 0 {
+			c.queryBuckets = buckets
+		}
+	}
+}
+
+// WithPersistBuckets sets the buckets of the persistence duration histogram, in seconds.
+// They default to prometheus.DefBuckets.
+func WithPersistBuckets(buckets []float64) Option {
+	return func(c *config) {
+		if len(buckets) > 0 {
+			c.persistBuckets = buckets
+		}
+	}
+}
+
+// NewCollector returns a Collector exporting the metrics of cache. It registers itself as
+// an observer of cache, so it should be created once per cache and then registered with a
+// prometheus.Registerer.
+func NewCollector(cache fscache.Operations, opts ...Option) *Collector {
+	cfg := config{
+		queryBuckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		persistBuckets: prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	desc := func(subsystem, name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricNamespace, subsystem, name), help, labels, cfg.constLabels)
+	}
+
+	c := &Collector{
+		cache: cache,
+
+		hits:        desc("keystore", "hits_total", "Number of KeyStore reads which found their key."),
+		misses:      desc("keystore", "misses_total", "Number of KeyStore reads which didn't find their key."),
+		hitRatio:    desc("keystore", "hit_ratio", "Ratio of the KeyStore reads which found their key."),
+		sets:        desc("keystore", "sets_total", "Number of KeyStore keys set or overwritten."),
+		deletes:     desc("keystore", "deletes_total", "Number of KeyStore keys deleted."),
+		expirations: desc("keystore", "expirations_total", "Number of KeyStore keys removed once expired."),
+		evictions:   desc("keystore", "evictions_total", "Number of KeyStore keys evicted to fit the capacity."),
+		entries:     desc("keystore", "entries", "Number of KeyStore entries."),
+		bytes:       desc("keystore", "bytes", "Estimated number of bytes held by the KeyStore."),
+		documents:   desc("datastore", "documents", "Number of documents of a DataStore namespace.", "namespace"),
+		docBytes:    desc("datastore", "bytes", "Estimated number of bytes held by a DataStore namespace.", "namespace"),
+		queries:     desc("datastore", "queries_total", "Number of queries run on a DataStore namespace.", "namespace"),
+
+		observer: &observer{
+			queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace:   metricNamespace,
+				Subsystem:   "datastore",
+				Name:        "query_duration_seconds",
+				Help:        "Duration of the queries run on a DataStore namespace.",
+				ConstLabels: cfg.constLabels,
+				Buckets:     cfg.queryBuckets,
+			}, []string{"namespace"}),
+			syncs: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace:   metricNamespace,
+				Subsystem:   "datastore",
+				Name:        "syncs_total",
+				Help:        "Number of DataStore documents synced to a database, by result.",
+				ConstLabels: cfg.constLabels,
+			}, []string{"database", "namespace", "result"}),
+			persistDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+				Namespace:   metricNamespace,
+				Subsystem:   "datastore",
+				Name:        "persist_duration_seconds",
+				Help:        "Duration of the persistence of the DataStore data.",
+				ConstLabels: cfg.constLabels,
+				Buckets:     cfg.persistBuckets,
+			}),
+			persistFailures: prometheus.NewCounter(prometheus.CounterOpts{
+				Namespace:   metricNamespace,
+				Subsystem:   "datastore",
+				Name:        "persist_failures_total",
+				Help:        "Number of failed persistences of the DataStore data.",
+				ConstLabels: cfg.constLabels,
+			}),
+		},
+	}
+
+	cache.Observe(c.observer)
+
+	return c
+}
+
+// Describe sends the descriptors of the metrics of the Collector, see prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.hits, c.misses, c.hitRatio, c.sets, c.deletes, c.expirations, c.evictions,
+		c.entries, c.bytes, c.documents, c.docBytes, c.queries,
+	} {
+		ch <- desc
+	}
+
+	c.observer.queryDuration.Describe(ch)
+	c.observer.syncs.Describe(ch)
+	c.observer.persistDuration.Describe(ch)
+	c.observer.persistFailures.Describe(ch)
+}
+
+// Collect sends the current metrics of the cache, see prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+
+	counter := func(desc *prometheus.Desc, value uint64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labels...)
+	}
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+
+	counter(c.hits, stats.Hits)
+	counter(c.misses, stats.Misses)
+	gauge(c.hitRatio, hitRatio(stats))
+	counter(c.sets, stats.Sets)
+	counter(c.deletes, stats.Deletes)
+	counter(c.expirations, stats.Expirations)
+	counter(c.evictions, stats.Evictions)
+	gauge(c.entries, float64(stats.Entries))
+	gauge(c.bytes, float64(stats.Bytes))
+
+	usage := c.cache.DataStore().MemoryUsage()
+	for name, ns := range stats.Namespaces {
+		gauge(c.documents, float64(ns.Documents), name)
+		gauge(c.docBytes, float64(usage[name]), name)
+		counter(c.queries, ns.Queries, name)
+	}
+
+	c.observer.queryDuration.Collect(ch)
+	c.observer.syncs.Collect(ch)
+	c.observer.persistDuration.Collect(ch)
+	c.observer.persistFailures.Collect(ch)
+}
+
+// hitRatio returns the ratio of the reads which were hits, zero before any read
+func hitRatio(stats fscache.Stats) float64 {
+	reads := stats.Hits + stats.Misses
+	if reads == 0 {
+		return 0
+	}
+
+	return float64(stats.Hits) / float64(reads)
+}
+
+// ObserveQuery records the duration of a query, see fscache.Observer
+func (o *observer) ObserveQuery(namespace string, duration time.Duration) {
+	o.queryDuration.WithLabelValues(namespace).Observe(duration.Seconds())
+}
+
+// ObserveSync counts a document synced to a database, see fscache.Observer
+func (o *observer) ObserveSync(database fscache.SyncDatabase, namespace string, err error) {
+	o.syncs.WithLabelValues(string(database), namespace, result(err)).Inc()
+}
+
+// ObservePersist records the duration of a persistence and counts the failed ones,
+// see fscache.Observer
+func (o *observer) ObservePersist(duration time.Duration, err error) {
+	o.persistDuration.Observe(duration.Seconds())
+	if err != nil {
+		o.persistFailures.Inc()
+	}
+}
+
+// result returns the value of the result label for err
+func result(err error) string {
+	if err != nil {
+		return resultFailure
+	}
+
+	return resultSuccess
+}
@@ -0,0 +1,123 @@
+package prometheus
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fscache "github.com/jiyamathias/fs-cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	fs := fscache.New(
+		fscache.WithCapacity(2, 0),
+		fscache.WithPersistPath(filepath.Join(t.TempDir(), "storage.json")),
+	)
+	collector := NewCollector(fs, WithConstLabels(prometheus.Labels{"cache": "test"}))
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	ks := fs.KeyStore()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, ks.Set(key, key))
+	}
+	_, err := ks.Get("c")
+	require.NoError(t, err)
+	_, err = ks.Get("a")
+	assert.ErrorIs(t, err, fscache.ErrKeyNotFound)
+	require.NoError(t, ks.Del("b"))
+
+	users := fs.DataStore().Namespace("user")
+	require.NoError(t, users.Create(map[string]any{"name": "jane"}))
+	_, err = users.Query(map[string]any{"name": "jane"})
+	require.NoError(t, err)
+
+	expected := `
+# HELP fscache_keystore_hit_ratio Ratio of the KeyStore reads which found their key.
+# TYPE fscache_keystore_hit_ratio gauge
+fscache_keystore_hit_ratio{cache="test"} 0.5
+# HELP fscache_keystore_hits_total Number of KeyStore reads which found their key.
+# TYPE fscache_keystore_hits_total counter
+fscache_keystore_hits_total{cache="test"} 1
+# HELP fscache_keystore_misses_total Number of KeyStore reads which didn't find their key.
+# TYPE fscache_keystore_misses_total counter
+fscache_keystore_misses_total{cache="test"} 1
+# HELP fscache_keystore_sets_total Number of KeyStore keys set or overwritten.
+# TYPE fscache_keystore_sets_total counter
+fscache_keystore_sets_total{cache="test"} 3
+# HELP fscache_keystore_deletes_total Number of KeyStore keys deleted.
+# TYPE fscache_keystore_deletes_total counter
+fscache_keystore_deletes_total{cache="test"} 1
+# HELP fscache_keystore_evictions_total Number of KeyStore keys evicted to fit the capacity.
+# TYPE fscache_keystore_evictions_total counter
+fscache_keystore_evictions_total{cache="test"} 1
+# HELP fscache_keystore_entries Number of KeyStore entries.
+# TYPE fscache_keystore_entries gauge
+fscache_keystore_entries{cache="test"} 1
+# HELP fscache_datastore_documents Number of documents of a DataStore namespace.
+# TYPE fscache_datastore_documents gauge
+fscache_datastore_documents{cache="test",namespace="users"} 1
+# HELP fscache_datastore_queries_total Number of queries run on a DataStore namespace.
+# TYPE fscache_datastore_queries_total counter
+fscache_datastore_queries_total{cache="test",namespace="users"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"fscache_keystore_hit_ratio",
+		"fscache_keystore_hits_total",
+		"fscache_keystore_misses_total",
+		"fscache_keystore_sets_total",
+		"fscache_keystore_deletes_total",
+		"fscache_keystore_evictions_total",
+		"fscache_keystore_entries",
+		"fscache_datastore_documents",
+		"fscache_datastore_queries_total",
+	))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		switch family.GetName() {
+		case "fscache_keystore_bytes":
+			assert.Equal(t, float64(ks.MemoryUsage()), family.GetMetric()[0].GetGauge().GetValue())
+		case "fscache_datastore_bytes":
+			assert.Equal(t, float64(users.MemoryUsage()), family.GetMetric()[0].GetGauge().GetValue())
+		case "fscache_datastore_query_duration_seconds":
+			assert.Equal(t, uint64(1), family.GetMetric()[0].GetHistogram().GetSampleCount())
+		}
+	}
+
+	require.NoError(t, fs.DataStore().Collection("user").Insert(map[string]any{"name": "Jane Doe"}))
+	require.NoError(t, fs.DataStore().Persist())
+	assert.Equal(t, 1, testutil.CollectAndCount(collector, "fscache_datastore_persist_duration_seconds"))
+	assert.Zero(t, testutil.ToFloat64(collector.observer.persistFailures))
+}
+
+func TestObserver(t *testing.T) {
+	collector := NewCollector(fscache.New())
+	o := collector.observer
+
+	o.ObserveSync(fscache.SyncSQL, "users", nil)
+	o.ObserveSync(fscache.SyncSQL, "users", nil)
+	o.ObserveSync(fscache.SyncMongoDB, "users", errors.New("connection refused"))
+	assert.Equal(t, float64(2), testutil.ToFloat64(o.syncs.WithLabelValues("sql", "users", "success")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(o.syncs.WithLabelValues("mongodb", "users", "failure")))
+
+	o.ObservePersist(time.Millisecond, errors.New("disk full"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(o.persistFailures))
+
+	o.ObserveQuery("users", 50*time.Microsecond)
+	o.ObserveQuery("orders", time.Millisecond)
+	assert.Equal(t, 2, testutil.CollectAndCount(o.queryDuration))
+}
+
+func TestHitRatio(t *testing.T) {
+	assert.Zero(t, hitRatio(fscache.Stats{}))
+	assert.Equal(t, 0.75, hitRatio(fscache.Stats{Hits: 3, Misses: 1}))
+}
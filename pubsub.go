@@ -0,0 +1,224 @@
+package fscache
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// SlowConsumerDrop drops the messages published while the buffer of a subscriber is full
+	SlowConsumerDrop SlowConsumerPolicy = iota
+	// SlowConsumerBlock makes Publish() wait until the subscriber has room for the message
+	// or unsubscribes, so a slow subscriber slows down the publishers
+	SlowConsumerBlock
+	// SlowConsumerDisconnect ends the subscription of a subscriber whose buffer is full,
+	// its message channel gets closed
+	SlowConsumerDisconnect
+)
+
+// defaultPubSubBufferSize is the number of messages buffered per subscriber by default
+const defaultPubSubBufferSize = 64
+
+type (
+	// Message is a message received by a subscriber
+	Message struct {
+		// Channel is the channel the message was published to
+		Channel string
+		// Pattern is the pattern that matched the channel for a PSubscribe() subscription,
+		// it is empty for a Subscribe() subscription
+		Pattern string
+		// Payload is the message as published
+		Payload any
+	}
+
+	// SlowConsumerPolicy decides what Publish() does when the buffer of a subscriber is full
+	SlowConsumerPolicy int
+
+	// pubSub routes the published messages to the subscribers of their channel
+	pubSub struct {
+		mu         sync.RWMutex
+		channels   map[string]map[*subscriber]struct{}
+		patterns   map[string]map[*subscriber]struct{}
+		bufferSize int
+		policy     SlowConsumerPolicy
+	}
+
+	// subscriber is a subscription to channels or patterns, messages are delivered on ch
+	subscriber struct {
+		ch chan Message
+		// mu guards ch against being closed while a message is being sent
+		mu     sync.RWMutex
+		closed bool
+		// done is closed first when unsubscribing, it releases the blocked publishers
+		done     chan struct{}
+		once     sync.Once
+		channels []string
+		patterns []string
+	}
+)
+
+// newPubSub returns a pubSub buffering bufferSize messages per subscriber
+func newPubSub(bufferSize int, policy SlowConsumerPolicy) *pubSub {
+	return &pubSub{
+		channels:   make(map[string]map[*subscriber]struct{}),
+		patterns:   make(map[string]map[*subscriber]struct{}),
+		bufferSize: bufferSize,
+		policy:     policy,
+	}
+}
+
+// Publish() sends a message to the subscribers of a channel, including the subscribers
+// of a pattern matching the channel, and returns the number of subscribers it was delivered to.
+// When the buffer of a subscriber is full, the slow consumer policy set with WithPubSub() applies.
+func (c *Cache) Publish(channel string, msg any) int {
+	return c.pubsub.publish(channel, msg)
+}
+
+// Subscribe() subscribes to channels and returns the channel the messages are delivered on.
+// The subscription lasts until ctx is done or the cache is closed, the message channel is then closed.
+func (c *Cache) Subscribe(ctx context.Context, channels ...string) <-chan Message {
+	return c.pubsub.subscribe(ctx, c.lifecycle, channels, nil)
+}
+
+// PSubscribe() subscribes to the channels matching Redis glob-style patterns, like "user:*",
+// see Subscribe(). A channel matching several patterns gets its messages delivered once per pattern.
+func (c *Cache) PSubscribe(ctx context.Context, patterns ...string) <-chan Message {
+	return c.pubsub.subscribe(ctx, c.lifecycle, nil, patterns)
+}
+
+// subscribe registers a subscriber and unsubscribes it once ctx is done or the lifecycle stops
+func (ps *pubSub) subscribe(ctx context.Context, lc *lifecycle, channels, patterns []string) <-chan Message {
+	sub := &subscriber{
+		ch:       make(chan Message, ps.bufferSize),
+		done:     make(chan struct{}),
+		channels: channels,
+		patterns: patterns,
+	}
+
+	ps.mu.Lock()
+	for _, channel := range channels {
+		if ps.channels[channel] == nil {
+			ps.channels[channel] = make(map[*subscriber]struct{})
+		}
+		ps.channels[channel][sub] = struct{}{}
+	}
+	for _, pattern := range patterns {
+		if ps.patterns[pattern] == nil {
+			ps.patterns[pattern] = make(map[*subscriber]struct{})
+		}
+		ps.patterns[pattern][sub] = struct{}{}
+	}
+	ps.mu.Unlock()
+
+	started := lc.goroutine(func(done <-chan struct{}) {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		case <-sub.done:
+		}
+		ps.unsubscribe(sub)
+	})
+	if !started {
+		ps.unsubscribe(sub)
+	}
+
+	return sub.ch
+}
+
+// unsubscribe removes the subscriber and closes its message channel, it is safe to call
+// more than once
+func (ps *pubSub) unsubscribe(sub *subscriber) {
+	sub.once.Do(func() {
+		ps.mu.Lock()
+		for _, channel := range sub.channels {
+			delete(ps.channels[channel], sub)
+			if len(ps.channels[channel]) == 0 {
+				delete(ps.channels, channel)
+			}
+		}
+		for _, pattern := range sub.patterns {
+			delete(ps.patterns[pattern], sub)
+			if len(ps.patterns[pattern]) == 0 {
+				delete(ps.patterns, pattern)
+			}
+		}
+		ps.mu.Unlock()
+
+		close(sub.done)
+
+		sub.mu.Lock()
+		sub.closed = true
+		close(sub.ch)
+		sub.mu.Unlock()
+	})
+}
+
+// publish delivers the message to the subscribers of the channel and of the matching
+// patterns. The subscribers are collected under the lock but delivered to outside of it,
+// so a blocked delivery doesn't hold up subscriptions.
+func (ps *pubSub) publish(channel string, payload any) int {
+	type delivery struct {
+		sub *subscriber
+		msg Message
+	}
+
+	ps.mu.RLock()
+	var deliveries []delivery
+	for sub := range ps.channels[channel] {
+		deliveries = append(deliveries, delivery{sub: sub, msg: Message{Channel: channel, Payload: payload}})
+	}
+	for pattern, subs := range ps.patterns {
+		if !matchPattern(pattern, channel) {
+			continue
+		}
+		for sub := range subs {
+			deliveries = append(deliveries, delivery{sub: sub, msg: Message{Channel: channel, Pattern: pattern, Payload: payload}})
+		}
+	}
+	ps.mu.RUnlock()
+
+	var delivered int
+	for _, d := range deliveries {
+		ok, disconnect := ps.deliver(d.sub, d.msg)
+		if ok {
+			delivered++
+		}
+		if disconnect {
+			ps.unsubscribe(d.sub)
+		}
+	}
+
+	return delivered
+}
+
+// deliver sends the message to the subscriber, applying the slow consumer policy when its
+// buffer is full. It reports whether the message was delivered and whether the subscriber
+// must be disconnected.
+func (ps *pubSub) deliver(sub *subscriber, msg Message) (bool, bool) {
+	sub.mu.RLock()
+	defer sub.mu.RUnlock()
+
+	if sub.closed {
+		return false, false
+	}
+
+	select {
+	case sub.ch <- msg:
+		return true, false
+	default:
+	}
+
+	switch ps.policy {
+	case SlowConsumerBlock:
+		select {
+		case sub.ch <- msg:
+			return true, false
+		case <-sub.done:
+			return false, false
+		}
+	case SlowConsumerDisconnect:
+		return false, true
+	default:
+		return false, false
+	}
+}
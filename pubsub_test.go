@@ -0,0 +1,157 @@
+package fscache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPubSub returns a cache for Pub/Sub tests, closed at the end of the test
+func newTestPubSub(t *testing.T, opts ...Option) Operations {
+	opts = append(opts, WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	fs := New(opts...)
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+
+	return fs
+}
+
+// waitBlockedPublisher waits until a publisher is delivering a message to the single
+// subscriber of the channel, which it holds the read lock of until it is done
+func waitBlockedPublisher(t *testing.T, fs Operations, channel string) {
+	t.Helper()
+
+	ps := fs.(*Cache).pubsub
+	ps.mu.RLock()
+	var sub *subscriber
+	for s := range ps.channels[channel] {
+		sub = s
+	}
+	ps.mu.RUnlock()
+	require.NotNil(t, sub)
+
+	require.Eventually(t, func() bool {
+		if sub.mu.TryLock() {
+			sub.mu.Unlock()
+			return false
+		}
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	fs := newTestPubSub(t)
+	ctx := context.Background()
+
+	users := fs.Subscribe(ctx, "users", "orders")
+	pattern := fs.PSubscribe(ctx, "user*")
+
+	assert.Equal(t, 2, fs.Publish("users", "invalidate:1"))
+	assert.Equal(t, Message{Channel: "users", Payload: "invalidate:1"}, <-users)
+	assert.Equal(t, Message{Channel: "users", Pattern: "user*", Payload: "invalidate:1"}, <-pattern)
+
+	assert.Equal(t, 1, fs.Publish("orders", 42))
+	assert.Equal(t, Message{Channel: "orders", Payload: 42}, <-users)
+
+	assert.Zero(t, fs.Publish("nobody", "hello"))
+}
+
+func TestSubscribeContext(t *testing.T) {
+	fs := newTestPubSub(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := fs.Subscribe(ctx, "channel")
+	cancel()
+
+	// the message channel is closed once the context is done
+	_, ok := <-messages
+	assert.False(t, ok)
+	assert.Zero(t, fs.Publish("channel", "hello"))
+	assert.Empty(t, fs.(*Cache).pubsub.channels)
+}
+
+func TestSubscribeClose(t *testing.T) {
+	fs := New(WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	messages := fs.PSubscribe(context.Background(), "*")
+
+	require.NoError(t, fs.Close(context.Background()))
+	_, ok := <-messages
+	assert.False(t, ok)
+
+	// subscribing to a closed cache returns a closed channel
+	_, ok = <-fs.Subscribe(context.Background(), "channel")
+	assert.False(t, ok)
+}
+
+func TestSlowConsumerDrop(t *testing.T) {
+	fs := newTestPubSub(t, WithPubSub(2, SlowConsumerDrop))
+	messages := fs.Subscribe(context.Background(), "channel")
+
+	assert.Equal(t, 1, fs.Publish("channel", 1))
+	assert.Equal(t, 1, fs.Publish("channel", 2))
+	assert.Equal(t, 0, fs.Publish("channel", 3))
+
+	assert.Equal(t, 1, (<-messages).Payload)
+	assert.Equal(t, 2, (<-messages).Payload)
+
+	// the subscription goes on once there is room again
+	assert.Equal(t, 1, fs.Publish("channel", 4))
+	assert.Equal(t, 4, (<-messages).Payload)
+}
+
+func TestSlowConsumerBlock(t *testing.T) {
+	fs := newTestPubSub(t, WithPubSub(1, SlowConsumerBlock))
+	messages := fs.Subscribe(context.Background(), "channel")
+
+	assert.Equal(t, 1, fs.Publish("channel", 1))
+
+	published := make(chan int)
+	go func() {
+		published <- fs.Publish("channel", 2)
+	}()
+
+	waitBlockedPublisher(t, fs, "channel")
+	select {
+	case <-published:
+		t.Fatal("Publish() returned while the subscriber buffer was full")
+	default:
+	}
+
+	assert.Equal(t, 1, (<-messages).Payload)
+	assert.Equal(t, 1, <-published)
+	assert.Equal(t, 2, (<-messages).Payload)
+}
+
+func TestSlowConsumerBlockUnsubscribe(t *testing.T) {
+	fs := newTestPubSub(t, WithPubSub(0, SlowConsumerBlock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fs.Subscribe(ctx, "channel")
+
+	published := make(chan int)
+	go func() {
+		published <- fs.Publish("channel", 1)
+	}()
+
+	// a blocked publisher is released when the subscriber goes away
+	waitBlockedPublisher(t, fs, "channel")
+	cancel()
+	assert.Equal(t, 0, <-published)
+}
+
+func TestSlowConsumerDisconnect(t *testing.T) {
+	fs := newTestPubSub(t, WithPubSub(1, SlowConsumerDisconnect))
+	messages := fs.Subscribe(context.Background(), "channel")
+
+	assert.Equal(t, 1, fs.Publish("channel", 1))
+	assert.Equal(t, 0, fs.Publish("channel", 2))
+
+	// the buffered message is still delivered, then the channel is closed
+	assert.Equal(t, 1, (<-messages).Payload)
+	_, ok := <-messages
+	assert.False(t, ok)
+	assert.Zero(t, fs.Publish("channel", 3))
+}
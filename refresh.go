@@ -0,0 +1,338 @@
+package fscache
+
+import (
+	"container/heap"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshRetryInterval is how long a key waits before being refreshed again after a failure
+const refreshRetryInterval = time.Second
+
+type (
+	// RefreshFunc reloads the value of a stale key, see RegisterRefresh()
+	RefreshFunc func(ctx context.Context, key string) (any, error)
+
+	// keyStoreRefresh holds the refresh functions and the refreshes in flight
+	keyStoreRefresh struct {
+		mu       sync.Mutex
+		keys     map[string]RefreshFunc
+		prefixes map[string]RefreshFunc
+		// running holds the keys being refreshed so a key is refreshed once at a time
+		running map[string]struct{}
+		// retries holds the time the keys whose refresh failed can be refreshed again at
+		retries map[string]time.Time
+	}
+
+	// softQueue is a min-heap of the keys of a shard ordered by the time they turn stale.
+	// The entries of the keys removed or set again are left in place and skipped once
+	// popped, so updating a key doesn't search the heap. The heap is compacted once it
+	// holds more than twice as many entries as the shard holds keys.
+	softQueue []softEntry
+
+	// softEntry is a key of a softQueue and the time it turns stale at
+	softEntry struct {
+		at  time.Time
+		key string
+	}
+)
+
+// newKeyStoreRefresh returns a keyStoreRefresh without any refresh function
+func newKeyStoreRefresh() *keyStoreRefresh {
+	return &keyStoreRefresh{
+		keys:     make(map[string]RefreshFunc),
+		prefixes: make(map[string]RefreshFunc),
+		running:  make(map[string]struct{}),
+		retries:  make(map[string]time.Time),
+	}
+}
+
+// stale reports whether the data object is stale at the given time.
+// A zero soft duration never turns stale.
+func (d KeyStoreData) stale(now time.Time) bool {
+	return !d.SoftDuration.IsZero() && !now.Before(d.SoftDuration)
+}
+
+// SetWithSoftTTL() adds a new data which turns stale after softTTL and expires after hardTTL.
+// A stale data is still served by Get() while it gets refreshed in the background with the
+// function registered for its key, see RegisterRefresh(). The refreshed data gets the same
+// soft and hard time to live, unless its time to live was changed with GetEx(), Expire(),
+// ExpireAt() or Persist(): the refreshes keep it then. A zero or negative hardTTL never
+// expires, a softTTL which is not positive or not shorter than hardTTL makes it a plain Set().
+func (ks *KeyStore) SetWithSoftTTL(key string, value any, softTTL, hardTTL time.Duration) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	if _, ok := ks.live(shard, key, now); ok {
+		shard.mu.Unlock()
+		return ErrKeyExists
+	}
+
+	ks.store(shard, key, softData(now, value, softTTL, hardTTL))
+	ks.notify(EventSet, key, nil, value)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// RegisterRefresh() sets the function refreshing key once it is stale, see SetWithSoftTTL().
+// A nil refresh unregisters it.
+func (ks *KeyStore) RegisterRefresh(key string, refresh RefreshFunc) {
+	ks.refresh.register(ks.refresh.keys, key, refresh)
+}
+
+// RegisterRefreshPrefix() sets the function refreshing the stale keys starting with prefix.
+// The function registered for a key takes precedence, then the one of the longest prefix.
+// A nil refresh unregisters it.
+func (ks *KeyStore) RegisterRefreshPrefix(prefix string, refresh RefreshFunc) {
+	ks.refresh.register(ks.refresh.prefixes, prefix, refresh)
+}
+
+// register sets or removes a refresh function of keys or prefixes
+func (r *keyStoreRefresh) register(funcs map[string]RefreshFunc, name string, refresh RefreshFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if refresh == nil {
+		delete(funcs, name)
+		return
+	}
+	funcs[name] = refresh
+}
+
+// lookup returns the refresh function of a key, nil if none is registered.
+// The caller must hold r.mu.
+func (r *keyStoreRefresh) lookup(key string) RefreshFunc {
+	if refresh, ok := r.keys[key]; ok {
+		return refresh
+	}
+
+	var match RefreshFunc
+	longest := -1
+	for prefix, refresh := range r.prefixes {
+		if len(prefix) > longest && strings.HasPrefix(key, prefix) {
+			match, longest = refresh, len(prefix)
+		}
+	}
+
+	return match
+}
+
+// revalidate refreshes a stale key in the background if it has a refresh function and
+// it is not being refreshed already. The refreshed value is only stored if the key
+// didn't change during the refresh.
+func (ks *KeyStore) revalidate(key string) {
+	r := ks.refresh
+	now := ks.clock.Now()
+
+	r.mu.Lock()
+	refresh := r.lookup(key)
+	if refresh == nil {
+		r.mu.Unlock()
+		return
+	}
+	if _, ok := r.running[key]; ok {
+		r.mu.Unlock()
+		return
+	}
+	if retry, ok := r.retries[key]; ok && now.Before(retry) {
+		r.mu.Unlock()
+		return
+	}
+	r.running[key] = struct{}{}
+	r.mu.Unlock()
+
+	started := ks.lifecycle.goroutine(func(done <-chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		err := ks.refreshKey(ctx, key, refresh)
+		retry := ks.clock.Now().Add(refreshRetryInterval)
+		if err != nil {
+			// queue the key again so that the refresh ahead retries it
+			shard := ks.shard(key)
+			shard.mu.Lock()
+			shard.pushSoft(softEntry{at: retry, key: key})
+			shard.mu.Unlock()
+		}
+
+		r.mu.Lock()
+		delete(r.running, key)
+		if err != nil {
+			r.retries[key] = retry
+		} else {
+			delete(r.retries, key)
+		}
+		r.mu.Unlock()
+	})
+	if !started {
+		r.mu.Lock()
+		delete(r.running, key)
+		r.mu.Unlock()
+	}
+}
+
+// refreshKey calls the refresh function of a stale key and stores its result
+func (ks *KeyStore) refreshKey(ctx context.Context, key string, refresh RefreshFunc) error {
+	data, ok := ks.peek(key)
+	if !ok || !data.stale(ks.clock.Now()) {
+		return nil
+	}
+
+	value, err := refresh(ctx, key)
+	if err != nil {
+		ks.logger.Info().Msgf("data object [%v] refresh error: %v", key, err)
+		return err
+	}
+
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	prev, ok := ks.live(shard, key, now)
+	if !ok || prev.Version != data.Version {
+		// the key changed or expired during the refresh, the refreshed value is outdated
+		shard.mu.Unlock()
+		return nil
+	}
+
+	// the refreshed data keeps the settings of the key, only the time to live set by
+	// SetWithSoftTTL() is renewed
+	data = prev
+	data.Value = value
+	data.SoftDuration = now.Add(prev.softTTL)
+	if prev.hardTTL > 0 {
+		data.Duration = now.Add(prev.hardTTL)
+	}
+	ks.store(shard, key, data)
+	ks.notify(EventOverWrite, key, prev.Value, value)
+	ks.evicted(key, prev.Value, EvictReplaced)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// refreshAhead refreshes the keys which turned stale since the last call, so that they are
+// reloaded before they expire without waiting for a Get() to come across them. Only the due
+// keys are popped from the shards, and those without a refresh function are dropped then:
+// a function registered after a key turned stale refreshes it on its next Get().
+func (ks *KeyStore) refreshAhead(now time.Time) {
+	var due []string
+	for _, shard := range ks.shards {
+		shard.mu.Lock()
+		for len(shard.softs) > 0 && !shard.softs[0].at.After(now) {
+			entry := heap.Pop(&shard.softs).(softEntry)
+			if data, ok := shard.items[entry.key]; ok && data.stale(now) && !data.expired(now) {
+				due = append(due, entry.key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	if len(due) == 0 || !ks.refresh.registered() {
+		return
+	}
+
+	for _, key := range due {
+		ks.revalidate(key)
+	}
+}
+
+// registered reports whether any refresh function is registered
+func (r *keyStoreRefresh) registered() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.keys) > 0 || len(r.prefixes) > 0
+}
+
+// pushSoft queues a key of the shard to be refreshed at the time of the entry, compacting
+// the queue once the entries left by the keys removed or set again outnumber the keys.
+// The caller must hold the write lock of the shard.
+func (s *keyStoreShard) pushSoft(entry softEntry) {
+	heap.Push(&s.softs, entry)
+	if len(s.softs) > 2*len(s.items) {
+		s.softs.compact(s.items)
+	}
+}
+
+// compact drops the entries of the keys which no longer turn stale or were set again
+// since they were queued, keeping the earliest entry of every key
+func (q *softQueue) compact(items map[string]KeyStoreData) {
+	earliest := make(map[string]time.Time)
+	for _, entry := range *q {
+		data, ok := items[entry.key]
+		if !ok || data.SoftDuration.IsZero() || entry.at.Before(data.SoftDuration) {
+			continue
+		}
+		if at, ok := earliest[entry.key]; !ok || entry.at.Before(at) {
+			earliest[entry.key] = entry.at
+		}
+	}
+
+	compacted := make(softQueue, 0, len(earliest))
+	for key, at := range earliest {
+		compacted = append(compacted, softEntry{at: at, key: key})
+	}
+	heap.Init(&compacted)
+	*q = compacted
+}
+
+// Len returns the number of entries of the queue, see heap.Interface
+func (q softQueue) Len() int {
+	return len(q)
+}
+
+// Less orders the entries by the time they turn stale, see heap.Interface
+func (q softQueue) Less(i, j int) bool {
+	return q[i].at.Before(q[j].at)
+}
+
+// Swap swaps two entries, see heap.Interface
+func (q softQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+// Push appends an entry, see heap.Interface
+func (q *softQueue) Push(entry any) {
+	*q = append(*q, entry.(softEntry))
+}
+
+// Pop removes the last entry, see heap.Interface
+func (q *softQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return entry
+}
+
+// softData returns a data object stored at now which turns stale after softTTL and
+// expires after hardTTL
+func softData(now time.Time, value any, softTTL, hardTTL time.Duration) KeyStoreData {
+	data := KeyStoreData{
+		Value:    value,
+		Duration: expiresAt(now, []time.Duration{hardTTL}),
+	}
+
+	if softTTL > 0 && (hardTTL <= 0 || softTTL < hardTTL) {
+		data.SoftDuration = now.Add(softTTL)
+		data.softTTL, data.hardTTL = softTTL, hardTTL
+	}
+
+	return data
+}
@@ -0,0 +1,322 @@
+package fscache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetWithSoftTTL(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	require.NoError(t, ks.SetWithSoftTTL("key4", "value4", time.Minute, time.Hour))
+	assert.ErrorIs(t, ks.SetWithSoftTTL("key4", "value4", time.Minute, time.Hour), ErrKeyExists)
+
+	data, err := ks.GetData("key4")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), data.SoftDuration)
+	assert.Equal(t, now.Add(time.Hour), data.Duration)
+
+	// a stale data is served until it expires
+	ks.clock = fixedClock{now: now.Add(30 * time.Minute)}
+	value, err := ks.Get("key4")
+	require.NoError(t, err)
+	assert.Equal(t, "value4", value)
+
+	ks.clock = fixedClock{now: now.Add(time.Hour)}
+	_, err = ks.Get("key4")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// a soft ttl not shorter than the hard one is ignored
+	require.NoError(t, ks.SetWithSoftTTL("key5", "value5", time.Hour, time.Minute))
+	data, err = ks.GetData("key5")
+	require.NoError(t, err)
+	assert.True(t, data.SoftDuration.IsZero())
+}
+
+func TestRevalidate(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	ks.RegisterRefreshPrefix("user:", func(_ context.Context, key string) (any, error) {
+		calls.Add(1)
+		<-release
+		return key + " refreshed", nil
+	})
+
+	require.NoError(t, ks.SetWithSoftTTL("user:1", "jane", time.Minute, time.Hour))
+	require.NoError(t, ks.Set("user:2", "john"))
+
+	// fresh data is not refreshed
+	_, err := ks.Get("user:1")
+	require.NoError(t, err)
+	assert.Zero(t, calls.Load())
+
+	// stale data is served while a single refresh runs in the background
+	ks.clock = fixedClock{now: now.Add(2 * time.Minute)}
+	for i := 0; i < 10; i++ {
+		value, err := ks.Get("user:1")
+		require.NoError(t, err)
+		assert.Equal(t, "jane", value)
+	}
+	close(release)
+
+	require.Eventually(t, func() bool {
+		value, _ := ks.Get("user:1")
+		return value == "user:1 refreshed"
+	}, time.Second, time.Millisecond)
+	assert.EqualValues(t, 1, calls.Load())
+
+	// the refreshed data gets the same soft and hard time to live
+	data, err := ks.GetData("user:1")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(3*time.Minute), data.SoftDuration)
+	assert.Equal(t, now.Add(2*time.Minute+time.Hour), data.Duration)
+
+	// data without a soft time to live is never refreshed
+	_, err = ks.Get("user:2")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestRevalidateKeepsNewerWrites(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	ks.RegisterRefresh("key4", func(context.Context, string) (any, error) {
+		close(started)
+		<-release
+		return "refreshed", nil
+	})
+
+	require.NoError(t, ks.SetWithSoftTTL("key4", "value4", time.Minute, time.Hour))
+	ks.clock = fixedClock{now: now.Add(2 * time.Minute)}
+	_, err := ks.Get("key4")
+	require.NoError(t, err)
+
+	<-started
+	require.NoError(t, ks.OverWrite("key4", "updated"))
+	close(release)
+
+	require.Eventually(t, func() bool {
+		ks.refresh.mu.Lock()
+		defer ks.refresh.mu.Unlock()
+		return len(ks.refresh.running) == 0
+	}, time.Second, time.Millisecond)
+
+	value, err := ks.Get("key4")
+	require.NoError(t, err)
+	assert.Equal(t, "updated", value)
+}
+
+func TestRefreshKeepsKeySettings(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	refresh := func(_ context.Context, key string) (any, error) {
+		return key + " refreshed", nil
+	}
+
+	require.NoError(t, ks.SetWithSoftTTL("renewed", "value", time.Minute, time.Hour))
+	require.NoError(t, ks.SetWithSoftTTL("deadline", "value", time.Minute, time.Hour))
+	require.NoError(t, ks.ExpireAt("deadline", now.Add(10*time.Minute)))
+	require.NoError(t, ks.SetWithSoftTTL("persisted", "value", time.Minute, time.Hour))
+	require.NoError(t, ks.Persist("persisted"))
+
+	// a soft key with a sliding time to live and tags
+	shard := ks.shard("sliding")
+	shard.mu.Lock()
+	data := softData(now, "value", time.Minute, 0)
+	data.sliding, data.deadline = 5*time.Minute, now.Add(time.Hour)
+	data.Duration = data.slideTo(now)
+	data.tags = []string{"tag"}
+	ks.store(shard, "sliding", data)
+	shard.mu.Unlock()
+
+	refreshed := now.Add(2 * time.Minute)
+	ks.clock = fixedClock{now: refreshed}
+	for _, key := range []string{"renewed", "deadline", "persisted", "sliding"} {
+		require.NoError(t, ks.refreshKey(context.Background(), key, refresh), key)
+
+		data, err := ks.GetData(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, key+" refreshed", data.Value, key)
+		assert.Equal(t, refreshed.Add(time.Minute), data.SoftDuration, key)
+	}
+
+	// only the hard time to live set by SetWithSoftTTL() is renewed
+	data, err := ks.GetData("renewed")
+	require.NoError(t, err)
+	assert.Equal(t, refreshed.Add(time.Hour), data.Duration)
+	data, err = ks.GetData("deadline")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(10*time.Minute), data.Duration)
+	data, err = ks.GetData("persisted")
+	require.NoError(t, err)
+	assert.True(t, data.Duration.IsZero())
+
+	data, err = ks.GetData("sliding")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, data.sliding)
+	assert.Equal(t, now.Add(time.Hour), data.deadline)
+	assert.Equal(t, []string{"tag"}, data.tags)
+	assert.Equal(t, []string{"sliding"}, ks.tags.lookup([]string{"tag"}))
+}
+
+func TestRefreshAhead(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	var calls atomic.Int32
+	errUpstream := errors.New("upstream down")
+	ks.RegisterRefresh("key4", func(context.Context, string) (any, error) {
+		if calls.Add(1) == 1 {
+			return nil, errUpstream
+		}
+		return "refreshed", nil
+	})
+	require.NoError(t, ks.SetWithSoftTTL("key4", "value4", time.Minute, time.Hour))
+
+	ks.refreshAhead(now)
+	assert.Zero(t, calls.Load())
+
+	// a failed refresh keeps the stale data and is retried later
+	ks.clock = fixedClock{now: now.Add(2 * time.Minute)}
+	ks.refreshAhead(ks.clock.Now())
+	require.Eventually(t, func() bool {
+		ks.refresh.mu.Lock()
+		defer ks.refresh.mu.Unlock()
+		return len(ks.refresh.retries) == 1
+	}, time.Second, time.Millisecond)
+
+	ks.refreshAhead(ks.clock.Now())
+	value, err := ks.Get("key4")
+	require.NoError(t, err)
+	assert.Equal(t, "value4", value)
+	assert.EqualValues(t, 1, calls.Load())
+
+	ks.clock = fixedClock{now: now.Add(2*time.Minute + refreshRetryInterval)}
+	ks.refreshAhead(ks.clock.Now())
+	require.Eventually(t, func() bool {
+		value, _ := ks.Get("key4")
+		return value == "refreshed"
+	}, time.Second, time.Millisecond)
+	assert.EqualValues(t, 2, calls.Load())
+
+	// unregistered keys are left stale
+	ks.RegisterRefresh("key4", nil)
+	ks.clock = fixedClock{now: now.Add(time.Hour)}
+	ks.refreshAhead(ks.clock.Now())
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestRefreshAheadQueue(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	queued := func(key string) []time.Time {
+		shard := ks.shard(key)
+		shard.mu.RLock()
+		defer shard.mu.RUnlock()
+
+		var at []time.Time
+		for _, entry := range shard.softs {
+			if entry.key == key {
+				at = append(at, entry.at)
+			}
+		}
+		return at
+	}
+
+	var calls atomic.Int32
+	ks.RegisterRefreshPrefix("user:", func(context.Context, string) (any, error) {
+		calls.Add(1)
+		return nil, errors.New("upstream down")
+	})
+	require.NoError(t, ks.SetWithSoftTTL("user:1", "jane", time.Minute, time.Hour))
+	require.NoError(t, ks.SetWithSoftTTL("other", "value", time.Minute, time.Hour))
+	assert.Len(t, queued("user:1"), 1)
+	assert.Len(t, queued("other"), 1)
+
+	// keys not due yet stay queued
+	ks.refreshAhead(now.Add(30 * time.Second))
+	assert.Len(t, queued("user:1"), 1)
+	assert.Len(t, queued("other"), 1)
+
+	// the due keys are popped, the one without a refresh function is dropped and
+	// the one whose refresh failed is queued again for a retry
+	ks.clock = fixedClock{now: now.Add(time.Minute)}
+	ks.refreshAhead(ks.clock.Now())
+	assert.Empty(t, queued("other"))
+	require.Eventually(t, func() bool {
+		at := queued("user:1")
+		return len(at) == 1 && at[0].Equal(now.Add(time.Minute+refreshRetryInterval))
+	}, time.Second, time.Millisecond)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestRefreshAheadQueueCompaction(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	queued := func() int {
+		n := 0
+		for _, shard := range ks.shards {
+			shard.mu.RLock()
+			n += len(shard.softs)
+			shard.mu.RUnlock()
+		}
+		return n
+	}
+
+	// a key per shard keeps the shards from being emptied
+	for i := 0; len(ks.KeysMatching("kept:*")) < len(ks.shards); i++ {
+		_ = ks.SetWithSoftTTL("kept:"+strconv.Itoa(i), i, time.Hour, 0)
+	}
+	kept := len(ks.KeysMatching("kept:*"))
+	require.Equal(t, kept, queued())
+
+	// the entries left by the keys deleted or set again don't pile up until they are due
+	for i := 0; i < 10000; i++ {
+		key := "churn:" + strconv.Itoa(i%100)
+		require.NoError(t, ks.SetWithSoftTTL(key, i, time.Hour, 0))
+		if i%2 == 0 {
+			require.NoError(t, ks.OverWrite(key, i))
+		}
+		require.NoError(t, ks.Del(key))
+	}
+	assert.LessOrEqual(t, queued(), 2*(ks.Size()+len(ks.shards)))
+
+	// the kept keys are still queued once
+	for i := 0; i < kept; i++ {
+		shard := ks.shard("kept:" + strconv.Itoa(i))
+		shard.mu.RLock()
+		n := 0
+		for _, entry := range shard.softs {
+			if entry.key == "kept:"+strconv.Itoa(i) {
+				n++
+			}
+		}
+		shard.mu.RUnlock()
+		assert.Equal(t, 1, n)
+	}
+
+	// clearing the storage empties the queues
+	require.NoError(t, ks.Clear())
+	assert.Zero(t, queued())
+}
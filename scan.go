@@ -0,0 +1,109 @@
+package fscache
+
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+)
+
+// defaultScanCount is the number of keys Scan() aims at when no count is given
+const defaultScanCount = 10
+
+// KeysMatching() returns the keys matching a Redis glob-style pattern, like "user:*",
+// "h?llo" or "[ab]*", all keys for an empty pattern like Scan() and Iter(). A backslash
+// escapes the special characters.
+func (ks *KeyStore) KeysMatching(pattern string) []string {
+	keys := []string{}
+	ks.forEach(func(key string, _ KeyStoreData) bool {
+		if keyMatches(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+
+	return keys
+}
+
+// Scan() iterates the keys incrementally. Starting with a zero cursor, every call returns
+// some keys matching the pattern, all keys for an empty pattern, along with the cursor to pass
+// to the next call. The iteration is complete once the returned cursor is zero.
+//
+// Like Redis, count is a hint of how many keys to return: a call goes through the shards of
+// the storage, read-locking one at a time, and stops once it collected count keys. Only the
+// keys sharing a position with the last one returned can come on top. The cursor tells the
+// shard to resume from and the position in it, the keys of a shard being ordered by a hash.
+// A key stored for the whole iteration is returned exactly once, a key set or deleted in
+// the meantime may or may not be returned.
+func (ks *KeyStore) Scan(cursor uint64, pattern string, count int) ([]string, uint64) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	shardBits := bits.Len(uint(len(ks.shards) - 1))
+	positionBits := 64 - shardBits
+	from := cursor & (1<<positionBits - 1)
+
+	keys := []string{}
+	type entry struct {
+		position uint64
+		key      string
+	}
+
+	var entries []entry
+	now := ks.clock.Now()
+	for i := int(cursor >> positionBits); i < len(ks.shards); i++ {
+		entries = entries[:0]
+		shard := ks.shards[i]
+		shard.mu.RLock()
+		for key, data := range shard.items {
+			position := scanPosition(key, shardBits)
+			if position < from || data.expired(now) || !keyMatches(pattern, key) {
+				continue
+			}
+			entries = append(entries, entry{position: position, key: key})
+		}
+		shard.mu.RUnlock()
+
+		slices.SortFunc(entries, func(a, b entry) int {
+			return cmp.Compare(a.position, b.position)
+		})
+
+		for j, e := range entries {
+			keys = append(keys, e.key)
+			// the keys sharing a position are returned together since the cursor can't tell them apart
+			if len(keys) < count || (j+1 < len(entries) && entries[j+1].position == e.position) {
+				continue
+			}
+
+			if next := e.position + 1; next < 1<<positionBits {
+				return keys, uint64(i)<<positionBits | next
+			}
+			if i+1 < len(ks.shards) {
+				return keys, uint64(i+1) << positionBits
+			}
+			return keys, 0
+		}
+
+		from = 0
+	}
+
+	return keys, 0
+}
+
+// keyMatches reports whether a key matches the pattern of KeysMatching(), Scan() or Iter(),
+// an empty pattern matching every key
+func keyMatches(pattern, key string) bool {
+	return pattern == "" || matchPattern(pattern, key)
+}
+
+// scanPosition returns the position of a key in its shard during a Scan(), the hash of the
+// key without the bits taken by the shard index in the cursor
+func scanPosition(key string, shardBits int) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+
+	return hash >> shardBits
+}
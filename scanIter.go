@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package fscache
+
+import "iter"
+
+// Iter() returns an iterator over the keys matching a Redis glob-style pattern, all keys
+// for an empty pattern, and their values. Like Scan(), it goes through the storage one shard
+// at a time: the matching entries of a shard are copied under its read lock, which is
+// released before they are yielded, so the loop body may use the KeyStore.
+//
+//	for key, value := range ks.Iter("user:*") {
+//		fmt.Println(key, value)
+//	}
+func (ks *KeyStore) Iter(pattern string) iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		type entry struct {
+			key   string
+			value any
+		}
+
+		var entries []entry
+		for _, shard := range ks.shards {
+			entries = entries[:0]
+			now := ks.clock.Now()
+
+			shard.mu.RLock()
+			for key, data := range shard.items {
+				if data.expired(now) || !keyMatches(pattern, key) {
+					continue
+				}
+				entries = append(entries, entry{key: key, value: exposed(data.Value)})
+			}
+			shard.mu.RUnlock()
+
+			for _, e := range entries {
+				if !yield(e.key, e.value) {
+					return
+				}
+			}
+		}
+	}
+}
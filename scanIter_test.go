@@ -0,0 +1,34 @@
+//go:build go1.23
+
+package fscache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIter(t *testing.T) {
+	ks := newTestKeyStore()
+	require.NoError(t, ks.Set("user:1", "jane"))
+	require.NoError(t, ks.Set("user:2", "john"))
+
+	values := make(map[string]any)
+	for key, value := range ks.Iter("user:*") {
+		values[key] = value
+
+		// the KeyStore can be used in the loop
+		require.NoError(t, ks.OverWrite(key, "updated"))
+	}
+	assert.Equal(t, map[string]any{"user:1": "jane", "user:2": "john"}, values)
+
+	count := 0
+	for range ks.Iter("") {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,116 @@
+package fscache
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysMatching(t *testing.T) {
+	ks := newTestKeyStore()
+	for _, key := range []string{"user:1", "user:2", "users", "hello", "hallo", "hxllo", "a*b"} {
+		require.NoError(t, ks.Set(key, key))
+	}
+
+	assert.ElementsMatch(t, []string{"user:1", "user:2"}, ks.KeysMatching("user:*"))
+	assert.ElementsMatch(t, []string{"hello", "hallo", "hxllo"}, ks.KeysMatching("h?llo"))
+	assert.ElementsMatch(t, []string{"hello", "hallo"}, ks.KeysMatching("h[ae]llo"))
+	assert.ElementsMatch(t, []string{"a*b"}, ks.KeysMatching(`a\*b`))
+	assert.ElementsMatch(t, []string{"key1", "key2", "key3"}, ks.KeysMatching("key[0-9]"))
+	assert.Empty(t, ks.KeysMatching("missing*"))
+	// an empty pattern matches every key, like with Scan() and Iter()
+	assert.Len(t, ks.KeysMatching(""), 10)
+}
+
+func TestScan(t *testing.T) {
+	ks := newTestKeyStore()
+	for i := 0; i < 500; i++ {
+		require.NoError(t, ks.Set("user:"+strconv.Itoa(i), i))
+	}
+
+	seen := make(map[string]int)
+	var cursor uint64
+	calls := 0
+	for {
+		var keys []string
+		keys, cursor = ks.Scan(cursor, "user:*", 20)
+		for _, key := range keys {
+			seen[key]++
+		}
+		calls++
+
+		if cursor == 0 {
+			break
+		}
+		assert.GreaterOrEqual(t, len(keys), 20)
+	}
+
+	assert.Greater(t, calls, 1)
+	assert.Len(t, seen, 500)
+	for key, n := range seen {
+		assert.Equal(t, 1, n, key)
+	}
+
+	// an empty pattern matches every key
+	total := 0
+	cursor = 0
+	for {
+		var keys []string
+		keys, cursor = ks.Scan(cursor, "", 0)
+		total += len(keys)
+		if cursor == 0 {
+			break
+		}
+	}
+	assert.Equal(t, 503, total)
+
+}
+
+func TestScanCount(t *testing.T) {
+	ks := newTestKeyStore()
+	for i := 0; i < 10000; i++ {
+		require.NoError(t, ks.Set("user:"+strconv.Itoa(i), i))
+	}
+
+	// count bounds every call, even below the size of a shard
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		var keys []string
+		keys, cursor = ks.Scan(cursor, "user:*", 10)
+		for _, key := range keys {
+			seen[key]++
+		}
+
+		if cursor == 0 {
+			break
+		}
+		assert.Len(t, keys, 10)
+	}
+	assert.Len(t, seen, 10000)
+
+	// the keys deleted while scanning don't make the others be skipped or returned twice
+	seen = make(map[string]int)
+	cursor = 0
+	deleted := 0
+	for {
+		var keys []string
+		keys, cursor = ks.Scan(cursor, "user:*", 100)
+		for _, key := range keys {
+			seen[key]++
+		}
+		for n := 0; n < 100 && deleted < 5000; n++ {
+			require.NoError(t, ks.Del("user:"+strconv.Itoa(deleted)))
+			deleted++
+		}
+
+		if cursor == 0 {
+			break
+		}
+	}
+	for i := 5000; i < 10000; i++ {
+		assert.Equal(t, 1, seen["user:"+strconv.Itoa(i)], i)
+	}
+}
@@ -0,0 +1,244 @@
+package fscache
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// keyStoreSet is the value of a key holding a set of distinct string members
+type keyStoreSet struct {
+	members map[string]struct{}
+	// bytes is the estimated size of the members
+	bytes int64
+}
+
+// SAdd() adds the members to the set stored at key and returns the number of members added,
+// members already in the set are ignored. A missing key is created as a set which never expires.
+func (ks *KeyStore) SAdd(key string, members ...string) (int, error) {
+	var added int
+	err := updateCollection(ks, key, newKeyStoreSet, func(s *keyStoreSet) error {
+		for _, member := range members {
+			if s.add(member) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// SRem() removes the members from the set stored at key and returns the number of members
+// removed. The key is deleted once its set is empty.
+func (ks *KeyStore) SRem(key string, members ...string) (int, error) {
+	var removed int
+	err := updateCollection(ks, key, nil, func(s *keyStoreSet) error {
+		for _, member := range members {
+			if s.remove(member) {
+				removed++
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrKeyNotFound) {
+		return 0, nil
+	}
+
+	return removed, err
+}
+
+// SMembers() returns the members of the set stored at key in no particular order,
+// an empty slice for a missing key
+func (ks *KeyStore) SMembers(key string) ([]string, error) {
+	members := []string{}
+	err := readCollection(ks, key, func(s *keyStoreSet) {
+		members = s.snapshot().([]string)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// SIsMember() reports whether member belongs to the set stored at key
+func (ks *KeyStore) SIsMember(key, member string) (bool, error) {
+	var found bool
+	err := readCollection(ks, key, func(s *keyStoreSet) {
+		_, found = s.members[member]
+	})
+
+	return found, err
+}
+
+// SCard() returns the number of members of the set stored at key, zero for a missing key
+func (ks *KeyStore) SCard(key string) (int, error) {
+	var n int
+	err := readCollection(ks, key, func(s *keyStoreSet) {
+		n = len(s.members)
+	})
+
+	return n, err
+}
+
+// SInter() returns the members belonging to all the sets stored at keys.
+// A missing key is an empty set, so it makes the intersection empty.
+func (ks *KeyStore) SInter(keys ...string) ([]string, error) {
+	result := []string{}
+	err := ks.readSets(keys, func(sets []*keyStoreSet) {
+		if len(sets) == 0 {
+			return
+		}
+
+		// walk the smallest set and look its members up in the others
+		smallest := sets[0]
+		for _, s := range sets[1:] {
+			if len(s.members) < len(smallest.members) {
+				smallest = s
+			}
+		}
+
+	members:
+		for member := range smallest.members {
+			for _, s := range sets {
+				if _, ok := s.members[member]; !ok {
+					continue members
+				}
+			}
+			result = append(result, member)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SUnion() returns the members belonging to any of the sets stored at keys
+func (ks *KeyStore) SUnion(keys ...string) ([]string, error) {
+	result := []string{}
+	err := ks.readSets(keys, func(sets []*keyStoreSet) {
+		union := make(map[string]struct{})
+		for _, s := range sets {
+			for member := range s.members {
+				union[member] = struct{}{}
+			}
+		}
+
+		for member := range union {
+			result = append(result, member)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SDiff() returns the members of the set stored at the first key which don't belong
+// to any of the sets stored at the other keys
+func (ks *KeyStore) SDiff(keys ...string) ([]string, error) {
+	result := []string{}
+	err := ks.readSets(keys, func(sets []*keyStoreSet) {
+		if len(sets) == 0 {
+			return
+		}
+
+	members:
+		for member := range sets[0].members {
+			for _, s := range sets[1:] {
+				if _, ok := s.members[member]; ok {
+					continue members
+				}
+			}
+			result = append(result, member)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// readSets calls fn with the sets stored at keys while holding the read locks of their
+// shards, so that fn sees all of them at the same point in time. The set of a missing
+// key is empty, ErrWrongType is returned if a key holds another kind of value.
+func (ks *KeyStore) readSets(keys []string, fn func(sets []*keyStoreSet)) error {
+	unlock := ks.rlockShards(keys...)
+	defer unlock()
+
+	now := ks.clock.Now()
+	sets := make([]*keyStoreSet, len(keys))
+	for i, key := range keys {
+		data, ok := ks.shard(key).items[key]
+		if !ok || data.expired(now) {
+			sets[i] = &keyStoreSet{}
+			continue
+		}
+
+		s, ok := data.Value.(*keyStoreSet)
+		if !ok {
+			return ErrWrongType
+		}
+		sets[i] = s
+	}
+
+	fn(sets)
+
+	return nil
+}
+
+// newKeyStoreSet returns an empty set
+func newKeyStoreSet() *keyStoreSet {
+	return &keyStoreSet{members: make(map[string]struct{})}
+}
+
+// len returns the number of members of the set
+func (s *keyStoreSet) len() int {
+	return len(s.members)
+}
+
+// snapshot returns the members of the set as a slice
+func (s *keyStoreSet) snapshot() any {
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// memSize returns the estimated bytes held by the set
+func (s *keyStoreSet) memSize() int64 {
+	return int64(unsafe.Sizeof(*s)) + s.bytes
+}
+
+// add adds a member and reports whether it was not in the set yet
+func (s *keyStoreSet) add(member string) bool {
+	if _, ok := s.members[member]; ok {
+		return false
+	}
+
+	s.members[member] = struct{}{}
+	s.bytes += stringSize(member)
+
+	return true
+}
+
+// remove removes a member and reports whether it was in the set
+func (s *keyStoreSet) remove(member string) bool {
+	if _, ok := s.members[member]; !ok {
+		return false
+	}
+
+	delete(s.members, member)
+	s.bytes -= stringSize(member)
+
+	return true
+}
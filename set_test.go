@@ -0,0 +1,100 @@
+package fscache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSAddSRem(t *testing.T) {
+	ks := newTestKeyStore()
+	before := ks.usage.bytes.Load()
+
+	added, err := ks.SAdd("tags", "go", "cache", "go")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	added, err = ks.SAdd("tags", "redis", "go")
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	members, err := ks.SMembers("tags")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"go", "cache", "redis"}, members)
+
+	n, err := ks.SCard("tags")
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	ok, err := ks.SIsMember("tags", "go")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = ks.SIsMember("tags", "java")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	removed, err := ks.SRem("tags", "go", "java")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	// the key is deleted with its last member
+	removed, err = ks.SRem("tags", "cache", "redis")
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	_, err = ks.Get("tags")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, before, ks.usage.bytes.Load())
+
+	removed, err = ks.SRem("missing", "a")
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func TestSetOperations(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.SAdd("set1", "a", "b", "c", "d")
+	require.NoError(t, err)
+	_, err = ks.SAdd("set2", "c")
+	require.NoError(t, err)
+	_, err = ks.SAdd("set3", "a", "c", "e")
+	require.NoError(t, err)
+
+	members, err := ks.SInter("set1", "set2", "set3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, members)
+
+	members, err = ks.SInter("set1", "missing")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	members, err = ks.SUnion("set1", "set2", "set3", "missing")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e"}, members)
+
+	members, err = ks.SDiff("set1", "set2", "set3")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"b", "d"}, members)
+
+	members, err = ks.SDiff("missing", "set1")
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	_, err = ks.SInter("set1", "key1")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
+
+func TestSetSnapshot(t *testing.T) {
+	ks := newTestKeyStore()
+	_, err := ks.SAdd("set", "a", "b")
+	require.NoError(t, err)
+
+	value, err := ks.Get("set")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, value)
+
+	_, err = ks.SAdd("key1", "a")
+	assert.ErrorIs(t, err, ErrWrongType)
+}
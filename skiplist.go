@@ -0,0 +1,202 @@
+package fscache
+
+import "math/rand/v2"
+
+const (
+	// skiplistMaxLevel bounds the number of levels of a skiplist node, enough for 2^64 elements
+	skiplistMaxLevel = 32
+	// skiplistP is the probability for a node to get one more level
+	skiplistP = 0.25
+)
+
+type (
+	// skiplist keeps the members of a sorted set ordered by score, then by member.
+	// Like the Redis zskiplist, every link records how many nodes it spans so the rank
+	// of a member and the member at a rank are found in O(log n).
+	skiplist struct {
+		head   *skiplistNode
+		tail   *skiplistNode
+		length int
+		level  int
+	}
+
+	// skiplistNode is a member of a skiplist
+	skiplistNode struct {
+		member   string
+		score    float64
+		backward *skiplistNode
+		levels   []skiplistLevel
+	}
+
+	// skiplistLevel is the link of a node to the next node at a level
+	skiplistLevel struct {
+		forward *skiplistNode
+		span    int
+	}
+)
+
+// newSkiplist returns an empty skiplist
+func newSkiplist() *skiplist {
+	return &skiplist{
+		head:  &skiplistNode{levels: make([]skiplistLevel, skiplistMaxLevel)},
+		level: 1,
+	}
+}
+
+// randomLevel returns the level of a new node, with a power law distribution
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+
+	return level
+}
+
+// less reports whether the node is ordered before the given score and member
+func (n *skiplistNode) less(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// after reports whether the node is ordered after the given score and member
+func (n *skiplistNode) after(score float64, member string) bool {
+	return n.score > score || (n.score == score && n.member > member)
+}
+
+// insert adds a member which must not already be in the skiplist
+func (s *skiplist) insert(member string, score float64) {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		if i < s.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > s.level {
+		for i := s.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = s.head
+			update[i].levels[i].span = s.length
+		}
+		s.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, levels: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+
+	// the levels above the new node now span it too
+	for i := level; i < s.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != s.head {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		s.tail = x
+	}
+	s.length++
+}
+
+// delete removes a member with its current score and reports whether it was found
+func (s *skiplist) delete(member string, score float64) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.less(score, member) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	for i := 0; i < s.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		s.tail = x.backward
+	}
+
+	for s.level > 1 && s.head.levels[s.level-1].forward == nil {
+		s.level--
+	}
+	s.length--
+
+	return true
+}
+
+// rank returns the 0-based rank of a member with its current score, -1 if it is not found
+func (s *skiplist) rank(member string, score float64) int {
+	rank := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !x.levels[i].forward.after(score, member) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if x != s.head && x.member == member {
+			return rank - 1
+		}
+	}
+
+	return -1
+}
+
+// byRank returns the node at the 0-based rank, which must be in range
+func (s *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= rank+1 {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		if traversed == rank+1 {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// firstFrom returns the first node with a score greater than or equal to min, nil if there is none
+func (s *skiplist) firstFrom(min float64) *skiplistNode {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.score < min {
+			x = x.levels[i].forward
+		}
+	}
+
+	return x.levels[0].forward
+}
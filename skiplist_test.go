@@ -0,0 +1,85 @@
+package fscache
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkiplist(t *testing.T) {
+	zsl := newSkiplist()
+	scores := make(map[string]float64)
+
+	// insert, update and delete at random and compare with a sorted reference
+	for i := 0; i < 2000; i++ {
+		member := strconv.Itoa(rand.IntN(300))
+		if score, ok := scores[member]; ok {
+			require.True(t, zsl.delete(member, score))
+			delete(scores, member)
+			if rand.IntN(2) == 0 {
+				continue
+			}
+		}
+
+		score := float64(rand.IntN(50))
+		zsl.insert(member, score)
+		scores[member] = score
+	}
+
+	expected := make([]ZMember, 0, len(scores))
+	for member, score := range scores {
+		expected = append(expected, ZMember{Member: member, Score: score})
+	}
+	slices.SortFunc(expected, func(a, b ZMember) int {
+		if a.Score != b.Score {
+			if a.Score < b.Score {
+				return -1
+			}
+			return 1
+		}
+		if a.Member < b.Member {
+			return -1
+		}
+		if a.Member > b.Member {
+			return 1
+		}
+		return 0
+	})
+
+	require.Equal(t, len(expected), zsl.length)
+	for rank, m := range expected {
+		assert.Equal(t, rank, zsl.rank(m.Member, m.Score))
+
+		node := zsl.byRank(rank)
+		require.NotNil(t, node)
+		assert.Equal(t, m.Member, node.member)
+	}
+
+	// the backward links walk the list in reverse
+	rank := len(expected) - 1
+	for x := zsl.tail; x != nil; x = x.backward {
+		assert.Equal(t, expected[rank].Member, x.member)
+		rank--
+	}
+	assert.Equal(t, -1, rank)
+
+	assert.False(t, zsl.delete("missing", 0))
+	assert.Equal(t, -1, zsl.rank("missing", 0))
+}
+
+func TestSkiplistFirstFrom(t *testing.T) {
+	zsl := newSkiplist()
+	zsl.insert("a", 1)
+	zsl.insert("b", 2)
+	zsl.insert("c", 2)
+	zsl.insert("d", 5)
+
+	assert.Equal(t, "a", zsl.firstFrom(0).member)
+	assert.Equal(t, "b", zsl.firstFrom(2).member)
+	assert.Equal(t, "d", zsl.firstFrom(2.5).member)
+	assert.Nil(t, zsl.firstFrom(6))
+}
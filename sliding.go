@@ -0,0 +1,130 @@
+package fscache
+
+import "time"
+
+// slidingWrites makes the writes of a WithSliding() view set a sliding time to live
+type slidingWrites struct {
+	enabled     bool
+	maxLifetime time.Duration
+}
+
+// SetSliding() adds a new data whose time to live is reset to window every time it is read
+// with Get() or GetMany(), like a login session. A positive maxLifetime caps how long the
+// data lives after being set however often it is read, a zero or negative one doesn't.
+// A zero or negative window makes it a Set() without time to live. Changing the time to
+// live with Expire(), ExpireAt(), Persist() or GetEx() stops the sliding. The other writes
+// set a sliding time to live when made through a WithSliding() view.
+func (ks *KeyStore) SetSliding(key string, value any, window, maxLifetime time.Duration) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	if _, ok := ks.live(shard, key, now); ok {
+		shard.mu.Unlock()
+		return ErrKeyExists
+	}
+
+	ks.store(shard, key, slidingData(now, value, window, maxLifetime))
+	ks.notify(EventSet, key, nil, value)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// OverWriteSliding() updates an already set value with a sliding time to live, see SetSliding()
+func (ks *KeyStore) OverWriteSliding(key string, value any, window, maxLifetime time.Duration) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	prev, ok := ks.live(shard, key, now)
+	if !ok {
+		shard.mu.Unlock()
+		return ErrKeyNotFound
+	}
+
+	ks.store(shard, key, slidingData(now, value, window, maxLifetime))
+	ks.notify(EventOverWrite, key, prev.Value, value)
+	ks.evicted(key, prev.Value, EvictReplaced)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// WithSliding() returns a view of the KeyStore whose writes set a sliding time to live: the
+// time to live given to Set(), OverWrite(), OverWriteWithKey(), SetNX(), SetXX(), GetSet(),
+// OverWriteIfVersion(), SetWithTags(), the Set() and OverWrite() of Tx(), or the time left
+// until the Duration of the data passed to SetMany(), becomes the window reset by every
+// read, see SetSliding(). The view shares the data and the settings of the KeyStore,
+// maxLifetime caps the life of the data it writes like the one of SetSliding(). Data
+// written without a time to live never expires, and the jitter set with SetTTLJitter()
+// applies to the window.
+//
+//	sessions := ks.WithSliding(24 * time.Hour)
+//	_ = sessions.SetWithTags("session:jane", session, 30*time.Minute, "user:jane")
+func (ks *KeyStore) WithSliding(maxLifetime time.Duration) *KeyStore {
+	return &KeyStore{
+		keyStore: ks.keyStore,
+		jitter:   ks.jitter,
+		sliding:  slidingWrites{enabled: true, maxLifetime: maxLifetime},
+	}
+}
+
+// slidingWrite turns the time to live of a data written at now through a WithSliding() view
+// into a sliding one whose window is the time left until its Duration. It returns the data
+// as is for the other views or if it never expires.
+func (ks *KeyStore) slidingWrite(now time.Time, data KeyStoreData) KeyStoreData {
+	if !ks.sliding.enabled || !data.Duration.After(now) {
+		return data
+	}
+
+	sliding := slidingData(now, data.Value, data.Duration.Sub(now), ks.sliding.maxLifetime)
+	data.Duration, data.sliding, data.deadline = sliding.Duration, sliding.sliding, sliding.deadline
+
+	return data
+}
+
+// slide pushes back the expiry of a data with a sliding time to live after it got read at now.
+// The data keeps its version since its value didn't change.
+func (ks *KeyStore) slide(key string, now time.Time) {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	data, ok := ks.live(shard, key, now)
+	if !ok || data.sliding <= 0 {
+		return
+	}
+
+	data.Duration = data.slideTo(now)
+	shard.items[key] = data
+}
+
+// slideTo returns the time a data with a sliding time to live read at now expires at
+func (d KeyStoreData) slideTo(now time.Time) time.Time {
+	expiry := now.Add(d.sliding)
+	if !d.deadline.IsZero() && d.deadline.Before(expiry) {
+		return d.deadline
+	}
+
+	return expiry
+}
+
+// slidingData returns a data object stored at now with a sliding time to live
+func slidingData(now time.Time, value any, window, maxLifetime time.Duration) KeyStoreData {
+	if window <= 0 {
+		return KeyStoreData{Value: value}
+	}
+
+	data := KeyStoreData{Value: value, sliding: window}
+	if maxLifetime > 0 {
+		data.deadline = now.Add(maxLifetime)
+	}
+	data.Duration = data.slideTo(now)
+
+	return data
+}
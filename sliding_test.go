@@ -0,0 +1,142 @@
+package fscache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetSliding(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	require.NoError(t, ks.SetSliding("session", "jane", 10*time.Minute, time.Hour))
+	assert.ErrorIs(t, ks.SetSliding("session", "jane", 10*time.Minute, time.Hour), ErrKeyExists)
+	data, err := ks.GetData("session")
+	require.NoError(t, err)
+
+	// every read resets the time to live, without changing the version
+	for i := 1; i <= 5; i++ {
+		ks.clock = fixedClock{now: now.Add(time.Duration(i) * 9 * time.Minute)}
+		value, err := ks.Get("session")
+		require.NoError(t, err)
+		assert.Equal(t, "jane", value)
+
+		ttl, err := ks.TTL("session")
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Minute, ttl)
+	}
+
+	read, err := ks.GetData("session")
+	require.NoError(t, err)
+	assert.Equal(t, data.Version, read.Version)
+
+	// reads can't push the expiry past the maximum lifetime
+	ks.clock = fixedClock{now: now.Add(54 * time.Minute)}
+	assert.Len(t, ks.GetMany([]string{"session"}), 1)
+	ttl, err := ks.TTL("session")
+	require.NoError(t, err)
+	assert.Equal(t, 6*time.Minute, ttl)
+
+	ks.clock = fixedClock{now: now.Add(time.Hour)}
+	_, err = ks.Get("session")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestSetSlidingIdle(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	require.NoError(t, ks.SetSliding("session", "jane", 10*time.Minute, 0))
+
+	ks.clock = fixedClock{now: now.Add(10 * time.Minute)}
+	_, err := ks.Get("session")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestOverWriteSliding(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	assert.ErrorIs(t, ks.OverWriteSliding("session", "jane", time.Minute, 0), ErrKeyNotFound)
+	require.NoError(t, ks.OverWriteSliding("key2", 20, time.Minute, 0))
+
+	ks.clock = fixedClock{now: now.Add(50 * time.Second)}
+	_, err := ks.Get("key2")
+	require.NoError(t, err)
+
+	// an explicit time to live stops the sliding
+	require.NoError(t, ks.Expire("key2", time.Minute))
+	ks.clock = fixedClock{now: now.Add(100 * time.Second)}
+	_, err = ks.Get("key2")
+	require.NoError(t, err)
+
+	ks.clock = fixedClock{now: now.Add(110 * time.Second)}
+	_, err = ks.Get("key2")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// an overwrite without sliding stops it too
+	require.NoError(t, ks.SetSliding("session", "jane", time.Minute, 0))
+	require.NoError(t, ks.OverWrite("session", "john"))
+	_, err = ks.Get("session")
+	require.NoError(t, err)
+	ttl, err := ks.TTL("session")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, ttl)
+}
+
+func TestWithSliding(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+	sliding := ks.WithSliding(time.Hour)
+
+	require.NoError(t, sliding.Set("set", "value", 10*time.Minute))
+	require.NoError(t, sliding.SetWithTags("tagged", "value", 10*time.Minute, "tag"))
+	_, err := sliding.SetMany([]map[string]KeyStoreData{{"many": {Value: "value", Duration: now.Add(10 * time.Minute)}}})
+	require.NoError(t, err)
+	require.NoError(t, sliding.Tx(func(tx *KeyStoreTx) error {
+		return tx.Set("tx", "value", 10*time.Minute)
+	}))
+	require.NoError(t, sliding.Set("forever", "value"))
+	require.NoError(t, ks.Set("plain", "value", 10*time.Minute))
+
+	keys := []string{"set", "tagged", "many", "tx"}
+	for i := 1; i <= 3; i++ {
+		ks.clock = fixedClock{now: now.Add(time.Duration(i) * 9 * time.Minute)}
+		assert.Len(t, ks.GetMany(keys), len(keys))
+	}
+
+	// the keys written through the view slid, the others didn't
+	for _, key := range keys {
+		ttl, err := ks.TTL(key)
+		require.NoError(t, err, key)
+		assert.Equal(t, 10*time.Minute, ttl, key)
+	}
+	_, err = ks.Get("plain")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	ttl, err := ks.TTL("forever")
+	require.NoError(t, err)
+	assert.Equal(t, NoExpiry, ttl)
+
+	// the maximum lifetime of the view caps the sliding
+	ks.clock = fixedClock{now: now.Add(time.Hour)}
+	for _, key := range keys {
+		_, err := ks.Get(key)
+		assert.ErrorIs(t, err, ErrKeyNotFound, key)
+	}
+
+	// the view follows the clock changed on the KeyStore afterwards
+	require.NoError(t, sliding.Set("late", "value", 10*time.Minute))
+	ttl, err = ks.TTL("late")
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, ttl)
+	data, err := ks.GetData("late")
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour+10*time.Minute), data.Duration)
+}
@@ -0,0 +1,131 @@
+package fscache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Stats reports the activity of the cache since it was created or since ResetStats(),
+	// and what it currently holds, see Operations.Stats()
+	Stats struct {
+		// Hits is the number of KeyStore reads which found their key
+		Hits uint64
+		// Misses is the number of KeyStore reads which didn't find their key
+		Misses uint64
+		// Sets is the number of KeyStore keys set or overwritten
+		Sets uint64
+		// Deletes is the number of KeyStore keys deleted
+		Deletes uint64
+		// Expirations is the number of KeyStore keys removed once expired
+		Expirations uint64
+		// Evictions is the number of KeyStore keys evicted to fit the capacity
+		Evictions uint64
+		// Entries is the number of KeyStore data objects, expired ones included until reclaimed
+		Entries int64
+		// Bytes is the estimated number of bytes held by the KeyStore, see MemoryUsage()
+		Bytes int64
+		// Namespaces holds the statistics of every DataStore namespace by name
+		Namespaces map[string]NamespaceStats
+	}
+
+	// NamespaceStats reports the documents and the queries of a DataStore namespace
+	NamespaceStats struct {
+		// Documents is the number of documents of the namespace
+		Documents int
+		// Queries is the number of queries run on the namespace, by Query(), Find() and First()
+		Queries uint64
+	}
+
+	// keyStoreStats counts the KeyStore operations, lock-free
+	keyStoreStats struct {
+		hits        atomic.Uint64
+		misses      atomic.Uint64
+		sets        atomic.Uint64
+		deletes     atomic.Uint64
+		expirations atomic.Uint64
+		evictions   atomic.Uint64
+	}
+
+	// dataStoreStats counts the DataStore queries per namespace. The counters are created
+	// once per namespace, after that counting a query takes no lock.
+	dataStoreStats struct {
+		queries sync.Map
+	}
+)
+
+// Stats() returns the statistics of the KeyStore and of the DataStore namespaces
+func (c *Cache) Stats() Stats {
+	ks, ds := &c.KeyStoreInstance, &c.DataStoreInstance
+
+	stats := Stats{
+		Hits:        ks.stats.hits.Load(),
+		Misses:      ks.stats.misses.Load(),
+		Sets:        ks.stats.sets.Load(),
+		Deletes:     ks.stats.deletes.Load(),
+		Expirations: ks.stats.expirations.Load(),
+		Evictions:   ks.stats.evictions.Load(),
+		Entries:     ks.usage.entries.Load(),
+		Bytes:       ks.usage.bytes.Load(),
+		Namespaces:  make(map[string]NamespaceStats),
+	}
+
+	ds.mu.RLock()
+	for namespace := range ds.schemas {
+		stats.Namespaces[namespace] = NamespaceStats{
+			Documents: len(ds.data[namespace]),
+			Queries:   ds.stats.counter(namespace).Load(),
+		}
+	}
+	ds.mu.RUnlock()
+
+	return stats
+}
+
+// ResetStats() sets the counters of the statistics back to zero. The entries, bytes and
+// documents, which are what the cache holds, are left as is.
+func (c *Cache) ResetStats() {
+	s := c.KeyStoreInstance.stats
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.sets.Store(0)
+	s.deletes.Store(0)
+	s.expirations.Store(0)
+	s.evictions.Store(0)
+
+	c.DataStoreInstance.stats.queries.Range(func(_, counter any) bool {
+		counter.(*atomic.Uint64).Store(0)
+		return true
+	})
+}
+
+// lookup counts a KeyStore read
+func (s *keyStoreStats) lookup(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+// record counts a change of a KeyStore key
+func (s *keyStoreStats) record(op EventOp) {
+	switch op {
+	case EventSet, EventOverWrite:
+		s.sets.Add(1)
+	case EventDel:
+		s.deletes.Add(1)
+	case EventExpire:
+		s.expirations.Add(1)
+	}
+}
+
+// counter returns the query counter of a namespace
+func (s *dataStoreStats) counter(namespace string) *atomic.Uint64 {
+	if counter, ok := s.queries.Load(namespace); ok {
+		return counter.(*atomic.Uint64)
+	}
+
+	counter, _ := s.queries.LoadOrStore(namespace, &atomic.Uint64{})
+	return counter.(*atomic.Uint64)
+}
@@ -0,0 +1,118 @@
+package fscache_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	fscache "github.com/jiyamathias/fs-cache"
+	"github.com/jiyamathias/fs-cache/fscachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	clock := fscachetest.NewClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	fs := fscache.New(
+		fscache.WithClock(clock),
+		fscache.WithCapacity(3, 0),
+		fscache.WithPersistPath(filepath.Join(t.TempDir(), "storage.json")),
+	)
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+	ks := fs.KeyStore()
+
+	require.NoError(t, ks.Set("key1", "value1"))
+	require.NoError(t, ks.Set("key2", "value2", time.Minute))
+	require.NoError(t, ks.OverWrite("key1", "updated"))
+
+	_, err := ks.Get("key1")
+	require.NoError(t, err)
+	_, err = ks.Get("missing")
+	assert.ErrorIs(t, err, fscache.ErrKeyNotFound)
+	_, err = ks.LRange("missing", 0, -1)
+	require.NoError(t, err)
+	require.NoError(t, ks.Del("key1"))
+
+	// the counters and the collections are counted like the other writes
+	_, err = ks.IncrBy("counter", 2)
+	require.NoError(t, err)
+	_, err = ks.IncrBy("counter", 3)
+	require.NoError(t, err)
+	require.NoError(t, ks.Del("counter"))
+	_, err = ks.LPush("list", "a", "b")
+	require.NoError(t, err)
+	_, err = ks.LPop("list")
+	require.NoError(t, err)
+	_, err = ks.LPop("list")
+	require.NoError(t, err)
+
+	// key2 expires, whether the active expiry or the read comes across it first
+	clock.Advance(time.Minute)
+	_, err = ks.Get("key2")
+	assert.ErrorIs(t, err, fscache.ErrKeyNotFound)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, ks.Set(key, key))
+	}
+
+	users := fs.DataStore().Namespace("user")
+	require.NoError(t, users.Create(map[string]any{"name": "jane"}))
+	require.NoError(t, users.Create(map[string]any{"name": "john"}))
+	_, err = users.Query(map[string]any{"name": "jane"})
+	require.NoError(t, err)
+	_, err = users.Query(nil)
+	require.NoError(t, err)
+	// the lookups of Update() and Delete() are not counted as queries
+	require.NoError(t, users.Update(map[string]any{"name": "john"}, map[string]any{"age": 30}))
+	require.NoError(t, users.Create(map[string]any{"name": "jim"}))
+	require.NoError(t, users.Delete(map[string]any{"name": "jim"}))
+
+	stats := fs.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	// 3 sets, 2 increments, a push and a pop leaving an element, 4 sets
+	assert.Equal(t, uint64(11), stats.Sets)
+	// key1, counter, and list once its last element was popped
+	assert.Equal(t, uint64(3), stats.Deletes)
+	assert.Equal(t, uint64(1), stats.Expirations)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, int64(3), stats.Entries)
+	assert.Equal(t, ks.MemoryUsage(), stats.Bytes)
+	assert.Equal(t, fscache.NamespaceStats{Documents: 2, Queries: 2}, stats.Namespaces["users"])
+
+	fs.ResetStats()
+	stats = fs.Stats()
+	assert.Zero(t, stats.Hits)
+	assert.Zero(t, stats.Misses)
+	assert.Zero(t, stats.Sets)
+	assert.Zero(t, stats.Deletes)
+	assert.Zero(t, stats.Evictions)
+	assert.Equal(t, int64(3), stats.Entries)
+	assert.Equal(t, fscache.NamespaceStats{Documents: 2}, stats.Namespaces["users"])
+}
+
+func TestStatsConcurrent(t *testing.T) {
+	fs := fscache.New(fscache.WithPersistPath(filepath.Join(t.TempDir(), "storage.json")))
+	t.Cleanup(func() { _ = fs.Close(context.Background()) })
+	ks := fs.KeyStore()
+	require.NoError(t, ks.Set("key", "value"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				_, _ = ks.Get("key")
+				_, _ = ks.Get("missing")
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := fs.Stats()
+	assert.Equal(t, uint64(8000), stats.Hits)
+	assert.Equal(t, uint64(8000), stats.Misses)
+}
@@ -0,0 +1,152 @@
+package fscache
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// tagIndex maps the tags to the keys of the data objects set with them, see SetWithTags()
+type tagIndex struct {
+	mu   sync.Mutex
+	keys map[string]map[string]struct{}
+}
+
+// newTagIndex returns an empty tagIndex
+func newTagIndex() *tagIndex {
+	return &tagIndex{
+		keys: make(map[string]map[string]struct{}),
+	}
+}
+
+// SetWithTags() adds a new data tagged with tags, see Set(). InvalidateTag() deletes all the
+// data objects having a tag at once. A zero or negative ttl never expires. The tags of a data
+// are kept when its time to live changes and dropped when it is deleted or set again.
+func (ks *KeyStore) SetWithTags(key string, value any, ttl time.Duration, tags ...string) error {
+	shard := ks.shard(key)
+	shard.mu.Lock()
+
+	now := ks.clock.Now()
+	if _, ok := ks.live(shard, key, now); ok {
+		shard.mu.Unlock()
+		return ErrKeyExists
+	}
+
+	tags = slices.Clone(tags)
+	slices.Sort(tags)
+	ks.store(shard, key, ks.slidingWrite(now, KeyStoreData{
+		Value:    value,
+		Duration: expiresAt(now, []time.Duration{ttl}),
+		tags:     slices.Compact(tags),
+	}))
+	ks.notify(EventSet, key, nil, value)
+	shard.mu.Unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// InvalidateTag() deletes all the data objects tagged with tag at once and returns how many
+// it deleted, see SetWithTags()
+func (ks *KeyStore) InvalidateTag(tag string) int {
+	return ks.InvalidateTags(tag)
+}
+
+// InvalidateTags() deletes all the data objects tagged with any of tags at once and returns
+// how many it deleted. No reader sees some of the data objects deleted and others not.
+func (ks *KeyStore) InvalidateTags(tags ...string) int {
+	locked := ks.tags.lookup(tags)
+	for len(locked) > 0 {
+		unlock := ks.lockShards(locked...)
+
+		// a key may have been tagged before the shards got locked, lock its shard too
+		keys := ks.tags.lookup(tags)
+		if !ks.coveredBy(keys, locked) {
+			unlock()
+			locked = keys
+			continue
+		}
+
+		removed := 0
+		now := ks.clock.Now()
+		for _, key := range keys {
+			shard := ks.shard(key)
+			if _, ok := ks.live(shard, key, now); !ok {
+				continue
+			}
+
+			data, _ := ks.remove(shard, key)
+			ks.notify(EventDel, key, data.Value, nil)
+			ks.evicted(key, data.Value, EvictDeleted)
+			removed++
+		}
+		unlock()
+
+		return removed
+	}
+
+	return 0
+}
+
+// coveredBy reports whether the shards of keys are all among the shards of locked
+func (ks *KeyStore) coveredBy(keys, locked []string) bool {
+	shards := make(map[int]struct{}, len(locked))
+	for _, key := range locked {
+		shards[ks.shardIndex(key)] = struct{}{}
+	}
+
+	for _, key := range keys {
+		if _, ok := shards[ks.shardIndex(key)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookup returns the keys tagged with any of tags
+func (ti *tagIndex) lookup(tags []string) []string {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	seen := make(map[string]struct{})
+	var keys []string
+	for _, tag := range tags {
+		for key := range ti.keys[tag] {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+// retag moves key from the tags it had to the tags it has now.
+// The caller must hold the write lock of the shard of key.
+func (ti *tagIndex) retag(key string, prev, tags []string) {
+	if len(prev) == 0 && len(tags) == 0 {
+		return
+	}
+
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	for _, tag := range prev {
+		delete(ti.keys[tag], key)
+		if len(ti.keys[tag]) == 0 {
+			delete(ti.keys, tag)
+		}
+	}
+
+	for _, tag := range tags {
+		keys, ok := ti.keys[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			ti.keys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
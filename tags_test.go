@@ -0,0 +1,108 @@
+package fscache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	ks := newTestKeyStore()
+
+	require.NoError(t, ks.SetWithTags("user:1:profile", "jane", time.Minute, "user:1"))
+	require.NoError(t, ks.SetWithTags("user:1:orders", []string{"o1"}, 0, "user:1", "orders"))
+	require.NoError(t, ks.SetWithTags("user:2:profile", "john", 0, "user:2"))
+	assert.ErrorIs(t, ks.SetWithTags("user:2:profile", "john", 0, "user:2"), ErrKeyExists)
+
+	assert.Equal(t, 2, ks.InvalidateTag("user:1"))
+	_, err := ks.Get("user:1:profile")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = ks.Get("user:1:orders")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	value, err := ks.Get("user:2:profile")
+	require.NoError(t, err)
+	assert.Equal(t, "john", value)
+
+	assert.Zero(t, ks.InvalidateTag("user:1"))
+	assert.Zero(t, ks.InvalidateTag("orders"))
+	assert.Zero(t, ks.InvalidateTag("missing"))
+	assert.Equal(t, 1, ks.InvalidateTags("user:1", "user:2"))
+	assert.Empty(t, ks.tags.keys)
+}
+
+func TestTagIndexConsistency(t *testing.T) {
+	ks := newTestKeyStore()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ks.clock = fixedClock{now: now}
+
+	require.NoError(t, ks.SetWithTags("deleted", 1, 0, "tag"))
+	require.NoError(t, ks.SetWithTags("expiring", 2, time.Minute, "tag"))
+	require.NoError(t, ks.SetWithTags("overwritten", 3, 0, "tag"))
+	require.NoError(t, ks.SetWithTags("extended", 4, time.Minute, "tag"))
+
+	require.NoError(t, ks.Del("deleted"))
+	require.NoError(t, ks.OverWrite("overwritten", 30))
+	require.NoError(t, ks.Persist("extended"))
+	assert.ElementsMatch(t, []string{"expiring", "extended"}, ks.tags.lookup([]string{"tag"}))
+
+	ks.clock = fixedClock{now: now.Add(time.Minute)}
+	_, err := ks.Get("expiring")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, []string{"extended"}, ks.tags.lookup([]string{"tag"}))
+
+	// the overwritten data lost its tag
+	assert.Equal(t, 1, ks.InvalidateTag("tag"))
+	value, err := ks.Get("overwritten")
+	require.NoError(t, err)
+	assert.Equal(t, 30, value)
+}
+
+func TestTagIndexEviction(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	ks.SetCapacity(2, 0)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, ks.SetWithTags("key"+strconv.Itoa(i), i, 0, "tag"))
+	}
+
+	assert.ElementsMatch(t, []string{"key3", "key4"}, ks.tags.lookup([]string{"tag"}))
+	assert.Equal(t, 2, ks.InvalidateTag("tag"))
+	assert.Zero(t, ks.Size())
+}
+
+func TestInvalidateTagConcurrent(t *testing.T) {
+	ks := newTestKeyStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := "key:" + strconv.Itoa(i) + ":" + strconv.Itoa(j)
+				assert.NoError(t, ks.SetWithTags(key, j, 0, "tag", "tag:"+strconv.Itoa(i)))
+			}
+		}()
+	}
+
+	wg.Add(1)
+	removed := 0
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			removed += ks.InvalidateTag("tag")
+		}
+	}()
+	wg.Wait()
+
+	removed += ks.InvalidateTag("tag")
+	assert.Equal(t, 800, removed)
+	assert.Empty(t, ks.tags.keys)
+	assert.Equal(t, 3, ks.Size())
+}
@@ -0,0 +1,295 @@
+package fscache
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrTxConflict a key watched by the transaction changed before it committed
+var ErrTxConflict = errors.New("transaction aborted, a watched key changed")
+
+type (
+	// KeyStoreTx is a transaction on the KeyStore, see KeyStore.Tx()
+	KeyStoreTx struct {
+		ks *KeyStore
+		// ops are the queued operations, replayed on the locked storage when committing
+		ops []txOp
+		// keys are the keys the queued operations write
+		keys []string
+		// watched holds the state of the watched keys when they were watched
+		watched map[string]txSlot
+		// view is the storage as seen by the transaction: the current data objects
+		// with the queued operations applied
+		view *txView
+	}
+
+	// txOp is an operation queued in a transaction, it applies itself to a view
+	txOp func(v *txView, now time.Time) error
+
+	// txSlot is the state of a key in a transaction
+	txSlot struct {
+		data   KeyStoreData
+		exists bool
+	}
+
+	// txView is a copy-on-write view of the storage: written keys are staged,
+	// other keys are read from the storage
+	txView struct {
+		staged map[string]txSlot
+		read   func(key string) (KeyStoreData, bool)
+	}
+)
+
+// Tx() runs fn in a transaction: the Set(), OverWrite(), Del() and Incr() calls made on tx
+// are queued and applied atomically once fn returns, or not at all if fn returns an error,
+// an operation fails or a watched key changed. The queued operations are checked against
+// the storage as seen by the transaction when they are called and once again when committing,
+// which is when their error is returned from Tx().
+//
+//	err := ks.Tx(func(tx *fscache.KeyStoreTx) error {
+//		if err := tx.Watch("from"); err != nil {
+//			return err
+//		}
+//		token, err := tx.Get("from")
+//		if err != nil {
+//			return err
+//		}
+//		if err := tx.Del("from"); err != nil {
+//			return err
+//		}
+//		return tx.Set("to", token)
+//	})
+func (ks *KeyStore) Tx(fn func(tx *KeyStoreTx) error) error {
+	tx := &KeyStoreTx{
+		ks:      ks,
+		watched: make(map[string]txSlot),
+		view:    newTxView(ks.peek),
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.commit()
+}
+
+// Watch() makes the transaction fail with ErrTxConflict if one of the keys is set, deleted,
+// expires or has its time to live changed between the call and the commit. Call it before
+// reading the keys to update them optimistically.
+func (tx *KeyStoreTx) Watch(keys ...string) error {
+	for _, key := range keys {
+		if _, ok := tx.watched[key]; ok {
+			continue
+		}
+
+		data, ok := tx.ks.peek(key)
+		tx.watched[key] = txSlot{data: data, exists: ok}
+	}
+
+	return nil
+}
+
+// Get() retrieves a data as seen by the transaction, with the queued operations applied
+func (tx *KeyStoreTx) Get(key string) (any, error) {
+	data, ok := tx.view.get(key)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return data.Value, nil
+}
+
+// Set() queues the addition of a new data, see KeyStore.Set()
+func (tx *KeyStoreTx) Set(key string, value any, duration ...time.Duration) error {
+	return tx.queue(key, func(v *txView, now time.Time) error {
+		if _, ok := v.get(key); ok {
+			return ErrKeyExists
+		}
+
+		v.put(key, tx.ks.slidingWrite(now, KeyStoreData{Value: value, Duration: expiresAt(now, duration)}))
+		return nil
+	})
+}
+
+// OverWrite() queues the update of an already set value, see KeyStore.OverWrite()
+func (tx *KeyStoreTx) OverWrite(key string, value any, duration ...time.Duration) error {
+	return tx.queue(key, func(v *txView, now time.Time) error {
+		if _, ok := v.get(key); !ok {
+			return ErrKeyNotFound
+		}
+
+		v.put(key, tx.ks.slidingWrite(now, KeyStoreData{Value: value, Duration: expiresAt(now, duration)}))
+		return nil
+	})
+}
+
+// Del() queues the deletion of a data, see KeyStore.Del()
+func (tx *KeyStoreTx) Del(key string) error {
+	return tx.queue(key, func(v *txView, _ time.Time) error {
+		if _, ok := v.get(key); !ok {
+			return ErrKeyNotFound
+		}
+
+		v.del(key)
+		return nil
+	})
+}
+
+// Incr() queues the increment of the integer stored at key by one, see IncrBy()
+func (tx *KeyStoreTx) Incr(key string) (int64, error) {
+	return tx.IncrBy(key, 1)
+}
+
+// Decr() queues the decrement of the integer stored at key by one, see IncrBy()
+func (tx *KeyStoreTx) Decr(key string) (int64, error) {
+	return tx.IncrBy(key, -1)
+}
+
+// IncrBy() queues the increment of the integer stored at key by delta, see KeyStore.IncrBy().
+// It returns the result as seen by the transaction, which is the committed result unless
+// the key is changed by someone else in the meantime. Watch the key to prevent that.
+func (tx *KeyStoreTx) IncrBy(key string, delta int64) (int64, error) {
+	if delta == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	var result int64
+	err := tx.queue(key, func(v *txView, _ time.Time) error {
+		data, ok := v.get(key)
+		if !ok {
+			data = KeyStoreData{Value: int64(0)}
+		}
+
+		value, sum, err := addInt(data.Value, delta)
+		if err != nil {
+			return err
+		}
+
+		data.Value = value
+		v.put(key, data)
+		result = sum
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+// queue applies the operation to the view of the transaction and queues it if it succeeded
+func (tx *KeyStoreTx) queue(key string, op txOp) error {
+	if err := op(tx.view, tx.ks.clock.Now()); err != nil {
+		return err
+	}
+
+	tx.ops = append(tx.ops, op)
+	tx.keys = append(tx.keys, key)
+
+	return nil
+}
+
+// commit locks the shards of the watched and written keys, checks the watched keys
+// didn't change, replays the queued operations on the storage and applies their result
+func (tx *KeyStoreTx) commit() error {
+	if len(tx.ops) == 0 && len(tx.watched) == 0 {
+		return nil
+	}
+
+	ks := tx.ks
+	keys := tx.keys
+	for key := range tx.watched {
+		keys = append(keys, key)
+	}
+
+	unlock := ks.lockShards(keys...)
+	now := ks.clock.Now()
+	live := func(key string) (KeyStoreData, bool) {
+		return ks.live(ks.shard(key), key, now)
+	}
+
+	for key, watched := range tx.watched {
+		data, ok := live(key)
+		if ok != watched.exists || data.Version != watched.data.Version {
+			unlock()
+			return ErrTxConflict
+		}
+	}
+
+	view := newTxView(live)
+	for _, op := range tx.ops {
+		if err := op(view, now); err != nil {
+			unlock()
+			return err
+		}
+	}
+
+	for key, slot := range view.staged {
+		shard := ks.shard(key)
+		prev, existed := shard.items[key]
+
+		switch {
+		case slot.exists:
+			ks.store(shard, key, slot.data)
+			if existed {
+				ks.notify(EventOverWrite, key, prev.Value, slot.data.Value)
+				ks.evicted(key, prev.Value, EvictReplaced)
+			} else {
+				ks.notify(EventSet, key, nil, slot.data.Value)
+			}
+		case existed:
+			ks.remove(shard, key)
+			ks.notify(EventDel, key, prev.Value, nil)
+			ks.evicted(key, prev.Value, EvictDeleted)
+		}
+	}
+	unlock()
+
+	ks.evict()
+
+	return nil
+}
+
+// peek returns the data object stored under key if it is not expired, with its value
+// exposed like the read paths do. It takes the read lock of the shard.
+func (ks *KeyStore) peek(key string) (KeyStoreData, bool) {
+	shard := ks.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	data, ok := shard.items[key]
+	if !ok || data.expired(ks.clock.Now()) {
+		return KeyStoreData{}, false
+	}
+
+	data.Value = exposed(data.Value)
+	return data, true
+}
+
+// newTxView returns a view reading the keys that are not staged with read
+func newTxView(read func(key string) (KeyStoreData, bool)) *txView {
+	return &txView{
+		staged: make(map[string]txSlot),
+		read:   read,
+	}
+}
+
+// get returns the data object of a key as seen by the view
+func (v *txView) get(key string) (KeyStoreData, bool) {
+	if slot, ok := v.staged[key]; ok {
+		return slot.data, slot.exists
+	}
+
+	return v.read(key)
+}
+
+// put stages a data object
+func (v *txView) put(key string, data KeyStoreData) {
+	v.staged[key] = txSlot{data: data, exists: true}
+}
+
+// del stages the deletion of a key
+func (v *txView) del(key string) {
+	v.staged[key] = txSlot{}
+}
@@ -0,0 +1,202 @@
+package fscache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTx(t *testing.T) {
+	ks := newTestKeyStore()
+
+	err := ks.Tx(func(tx *KeyStoreTx) error {
+		token, err := tx.Get("key1")
+		if err != nil {
+			return err
+		}
+
+		require.NoError(t, tx.Del("key1"))
+		require.NoError(t, tx.Set("key4", token, time.Minute))
+		require.NoError(t, tx.OverWrite("key3", false))
+
+		// the transaction sees its own writes
+		_, err = tx.Get("key1")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		value, err := tx.Get("key4")
+		require.NoError(t, err)
+		assert.Equal(t, "value1", value)
+
+		n, err := tx.IncrBy("key2", 5)
+		require.NoError(t, err)
+		assert.EqualValues(t, 15, n)
+		n, err = tx.Incr("counter")
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, n)
+
+		// nothing is applied before the commit
+		_, err = ks.Get("key4")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = ks.Get("key1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	values := ks.GetMany([]string{"key2", "key3", "key4", "counter"})
+	assert.ElementsMatch(t, []map[string]any{
+		{"key2": 15},
+		{"key3": false},
+		{"key4": "value1"},
+		{"counter": int64(1)},
+	}, values)
+
+	ttl, err := ks.TTL("key4")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+func TestTxAbort(t *testing.T) {
+	ks := newTestKeyStore()
+	errAbort := errors.New("abort")
+
+	err := ks.Tx(func(tx *KeyStoreTx) error {
+		require.NoError(t, tx.Set("key4", "value4"))
+		require.NoError(t, tx.Del("key1"))
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	_, err = ks.Get("key4")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = ks.Get("key1")
+	assert.NoError(t, err)
+
+	// failing operations return their error and are left out
+	err = ks.Tx(func(tx *KeyStoreTx) error {
+		assert.ErrorIs(t, tx.Set("key1", "value"), ErrKeyExists)
+		assert.ErrorIs(t, tx.OverWrite("missing", "value"), ErrKeyNotFound)
+		_, err := tx.Incr("key1")
+		assert.ErrorIs(t, err, ErrNotInteger)
+		return tx.Set("key4", "value4")
+	})
+	require.NoError(t, err)
+
+	value, err := ks.Get("key4")
+	require.NoError(t, err)
+	assert.Equal(t, "value4", value)
+}
+
+func TestTxCommitFailure(t *testing.T) {
+	ks := newTestKeyStore()
+
+	// an operation failing on commit aborts the whole transaction
+	err := ks.Tx(func(tx *KeyStoreTx) error {
+		require.NoError(t, tx.OverWrite("key1", "updated"))
+		require.NoError(t, tx.Set("key4", "value4"))
+
+		// key4 gets set outside of the transaction
+		require.NoError(t, ks.Set("key4", "other"))
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrKeyExists)
+
+	value, err := ks.Get("key1")
+	require.NoError(t, err)
+	assert.Equal(t, "value1", value)
+}
+
+func TestTxWatch(t *testing.T) {
+	ks := newTestKeyStore()
+
+	err := ks.Tx(func(tx *KeyStoreTx) error {
+		require.NoError(t, tx.Watch("key1", "missing"))
+		require.NoError(t, ks.OverWrite("key1", "changed"))
+		return tx.Set("key4", "value4")
+	})
+	assert.ErrorIs(t, err, ErrTxConflict)
+	_, err = ks.Get("key4")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// a watched missing key being set is a conflict too
+	err = ks.Tx(func(tx *KeyStoreTx) error {
+		require.NoError(t, tx.Watch("missing"))
+		require.NoError(t, ks.Set("missing", 1))
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrTxConflict)
+
+	// and so is a time to live change
+	err = ks.Tx(func(tx *KeyStoreTx) error {
+		require.NoError(t, tx.Watch("key2"))
+		require.NoError(t, ks.Expire("key2", time.Hour))
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrTxConflict)
+
+	// watched keys left alone commit fine
+	err = ks.Tx(func(tx *KeyStoreTx) error {
+		require.NoError(t, tx.Watch("key1", "key3"))
+		require.NoError(t, ks.OverWrite("key2", 20))
+		return tx.OverWrite("key1", "updated")
+	})
+	require.NoError(t, err)
+}
+
+func TestTxConcurrentTransfer(t *testing.T) {
+	ks := newTestKeyStore()
+	require.NoError(t, ks.Set("account:a", int64(1000)))
+	require.NoError(t, ks.Set("account:b", int64(0)))
+
+	// move one unit at a time with optimistic transactions, retrying on conflicts
+	transfer := func() {
+		for {
+			err := ks.Tx(func(tx *KeyStoreTx) error {
+				if err := tx.Watch("account:a", "account:b"); err != nil {
+					return err
+				}
+
+				a, err := tx.Get("account:a")
+				if err != nil {
+					return err
+				}
+				b, err := tx.Get("account:b")
+				if err != nil {
+					return err
+				}
+
+				if err := tx.OverWrite("account:a", a.(int64)-1); err != nil {
+					return err
+				}
+				return tx.OverWrite("account:b", b.(int64)+1)
+			})
+			if !errors.Is(err, ErrTxConflict) {
+				assert.NoError(t, err)
+				return
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				transfer()
+			}
+		}()
+	}
+	wg.Wait()
+
+	a, err := ks.Get("account:a")
+	require.NoError(t, err)
+	b, err := ks.Get("account:b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(600), a)
+	assert.Equal(t, int64(400), b)
+}
@@ -0,0 +1,159 @@
+package fscache
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrTypeMismatch is returned by a TypedKeyStore when a stored value can't be converted to its type
+var ErrTypeMismatch = errors.New("value type mismatch")
+
+// TypedKeyStore is a view of a KeyStore holding values of type T under keys sharing a prefix.
+// It saves type assertions on every read and reports values of another type with ErrTypeMismatch.
+type TypedKeyStore[T any] struct {
+	ks     *KeyStore
+	prefix string
+}
+
+// Typed returns a view of the KeyStore for values of type T stored under keys starting with prefix.
+// The keys passed to and returned by the view don't include the prefix.
+//
+//	users := fscache.Typed[User](fs.KeyStore(), "users:")
+//	_ = users.Set("jane", User{Name: "Jane Doe"}) // stored under "users:jane"
+//	user, err := users.Get("jane")
+func Typed[T any](ks *KeyStore, prefix string) *TypedKeyStore[T] {
+	return &TypedKeyStore[T]{
+		ks:     ks,
+		prefix: prefix,
+	}
+}
+
+// Set() adds a new value into the KeyStore, see KeyStore.Set()
+func (t *TypedKeyStore[T]) Set(key string, value T, duration ...time.Duration) error {
+	return t.ks.Set(t.prefix+key, value, duration...)
+}
+
+// OverWrite() updates an already set value, see KeyStore.OverWrite()
+func (t *TypedKeyStore[T]) OverWrite(key string, value T, duration ...time.Duration) error {
+	return t.ks.OverWrite(t.prefix+key, value, duration...)
+}
+
+// Get() retrieves a value from the KeyStore
+func (t *TypedKeyStore[T]) Get(key string) (T, error) {
+	value, err := t.ks.Get(t.prefix + key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return convert[T](key, value)
+}
+
+// GetMany() retrieves the values of the keys found in the KeyStore, keys not found are left out
+func (t *TypedKeyStore[T]) GetMany(keys []string) (map[string]T, error) {
+	values := make(map[string]T, len(keys))
+	for _, key := range keys {
+		value, err := t.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// Del() deletes a value from the KeyStore
+func (t *TypedKeyStore[T]) Del(key string) error {
+	return t.ks.Del(t.prefix + key)
+}
+
+// Keys() returns the keys of the view, without the prefix
+func (t *TypedKeyStore[T]) Keys() []string {
+	var keys []string
+	t.ks.forEach(func(key string, _ KeyStoreData) bool {
+		if strings.HasPrefix(key, t.prefix) {
+			keys = append(keys, strings.TrimPrefix(key, t.prefix))
+		}
+		return true
+	})
+
+	return keys
+}
+
+// Values() returns the values of the view
+func (t *TypedKeyStore[T]) Values() ([]T, error) {
+	var values []T
+	var err error
+	t.ks.forEach(func(key string, data KeyStoreData) bool {
+		if !strings.HasPrefix(key, t.prefix) {
+			return true
+		}
+
+		var value T
+		value, err = convert[T](strings.TrimPrefix(key, t.prefix), exposed(data.Value))
+		if err != nil {
+			return false
+		}
+
+		values = append(values, value)
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// convert returns the value as a T. Besides values of type T, it accepts numbers of
+// another numeric type as long as they convert without loss, e.g. a float64 decoded
+// from JSON holding an integer. Any other value yields ErrTypeMismatch.
+func convert[T any](key string, value any) (T, error) {
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+
+	var zero T
+	target := reflect.TypeOf(&zero).Elem()
+
+	// a nil value is the zero value of the types that can be nil
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return zero, nil
+		}
+	}
+
+	if value != nil {
+		v := reflect.ValueOf(value)
+		if isNumber(v.Kind()) && isNumber(target.Kind()) {
+			converted := v.Convert(target)
+			if converted.Convert(v.Type()).Equal(v) {
+				return converted.Interface().(T), nil
+			}
+		}
+	}
+
+	return zero, fmt.Errorf("%w: key %q holds %T, not %s", ErrTypeMismatch, key, value, target)
+}
+
+// isNumber reports whether the kind is an integer or a floating point number
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
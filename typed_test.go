@@ -0,0 +1,87 @@
+package fscache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTyped(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	users := Typed[user](&ks, "users:")
+
+	require.NoError(t, users.Set("jane", user{Name: "Jane Doe", Age: 30}, time.Minute))
+	require.NoError(t, users.Set("john", user{Name: "John Doe", Age: 35}))
+	require.NoError(t, ks.Set("other", "not a user"))
+
+	// the view stores the values under the prefixed keys
+	value, err := ks.Get("users:jane")
+	require.NoError(t, err)
+	assert.Equal(t, user{Name: "Jane Doe", Age: 30}, value)
+
+	jane, err := users.Get("jane")
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", jane.Name)
+
+	_, err = users.Get("missing")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	many, err := users.GetMany([]string{"jane", "john", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, many, 2)
+	assert.Equal(t, 35, many["john"].Age)
+
+	values, err := users.Values()
+	require.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.ElementsMatch(t, []string{"jane", "john"}, users.Keys())
+
+	require.NoError(t, users.OverWrite("john", user{Name: "John Doe", Age: 36}))
+	john, err := users.Get("john")
+	require.NoError(t, err)
+	assert.Equal(t, 36, john.Age)
+
+	require.NoError(t, users.Del("john"))
+	assert.Equal(t, []string{"jane"}, users.Keys())
+}
+
+func TestTypedMismatch(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	require.NoError(t, ks.Set("users:jane", "not a user"))
+
+	users := Typed[user](&ks, "users:")
+	_, err := users.Get("jane")
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = users.GetMany([]string{"jane"})
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = users.Values()
+	require.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestTypedConvert(t *testing.T) {
+	ks := newKeyStore(zerolog.Nop())
+	require.NoError(t, ks.Set("n:float", float64(42)))
+	require.NoError(t, ks.Set("n:fraction", 4.2))
+	require.NoError(t, ks.Set("n:nil", nil))
+
+	ints := Typed[int](&ks, "n:")
+	value, err := ints.Get("float")
+	require.NoError(t, err)
+	assert.Equal(t, 42, value)
+
+	_, err = ints.Get("fraction")
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	_, err = ints.Get("nil")
+	require.ErrorIs(t, err, ErrTypeMismatch)
+
+	pointers := Typed[*user](&ks, "n:")
+	pointer, err := pointers.Get("nil")
+	require.NoError(t, err)
+	assert.Nil(t, pointer)
+}
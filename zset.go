@@ -0,0 +1,241 @@
+package fscache
+
+import (
+	"errors"
+	"math"
+	"unsafe"
+)
+
+// ErrMemberNotFound member not found
+var ErrMemberNotFound = errors.New("member not found")
+
+type (
+	// ZMember is a member of a sorted set along with its score
+	ZMember struct {
+		Member string
+		Score  float64
+	}
+
+	// keyStoreZSet is the value of a key holding a sorted set. The scores map answers
+	// score lookups in O(1) while the skiplist keeps the members ordered for ranks and ranges.
+	keyStoreZSet struct {
+		scores map[string]float64
+		zsl    *skiplist
+		// bytes is the estimated size of the members
+		bytes int64
+	}
+)
+
+// ZAdd() adds the members to the sorted set stored at key, or updates their score if they
+// are already in the set, and returns the number of members added. A missing key is created
+// as a sorted set which never expires. It fails with ErrNotNumeric if a score is NaN.
+func (ks *KeyStore) ZAdd(key string, members ...ZMember) (int, error) {
+	for _, m := range members {
+		if math.IsNaN(m.Score) {
+			return 0, ErrNotNumeric
+		}
+	}
+
+	var added int
+	err := updateCollection(ks, key, newKeyStoreZSet, func(z *keyStoreZSet) error {
+		for _, m := range members {
+			if z.set(m.Member, m.Score) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// ZRem() removes the members from the sorted set stored at key and returns the number
+// of members removed. The key is deleted once its sorted set is empty.
+func (ks *KeyStore) ZRem(key string, members ...string) (int, error) {
+	var removed int
+	err := updateCollection(ks, key, nil, func(z *keyStoreZSet) error {
+		for _, member := range members {
+			if z.remove(member) {
+				removed++
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrKeyNotFound) {
+		return 0, nil
+	}
+
+	return removed, err
+}
+
+// ZScore() returns the score of a member of the sorted set stored at key.
+// It fails with ErrMemberNotFound if the key or the member doesn't exist.
+func (ks *KeyStore) ZScore(key, member string) (float64, error) {
+	var score float64
+	found := false
+	err := readCollection(ks, key, func(z *keyStoreZSet) {
+		score, found = z.scores[member]
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if !found {
+		return 0, ErrMemberNotFound
+	}
+
+	return score, nil
+}
+
+// ZRank() returns the 0-based rank of a member of the sorted set stored at key, members
+// being ordered from the lowest to the highest score. It fails with ErrMemberNotFound if
+// the key or the member doesn't exist.
+func (ks *KeyStore) ZRank(key, member string) (int, error) {
+	rank := -1
+	err := readCollection(ks, key, func(z *keyStoreZSet) {
+		if score, ok := z.scores[member]; ok {
+			rank = z.zsl.rank(member, score)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if rank < 0 {
+		return 0, ErrMemberNotFound
+	}
+
+	return rank, nil
+}
+
+// ZRange() returns the members of the sorted set stored at key from rank start to stop,
+// both included, ordered from the lowest to the highest score. Members with the same score
+// are ordered lexicographically. Indexes work like for LRange().
+func (ks *KeyStore) ZRange(key string, start, stop int) ([]ZMember, error) {
+	members := []ZMember{}
+	err := readCollection(ks, key, func(z *keyStoreZSet) {
+		start, stop, ok := listRange(start, stop, z.zsl.length)
+		if !ok {
+			return
+		}
+
+		members = make([]ZMember, 0, stop-start+1)
+		for x := z.zsl.byRank(start); len(members) < stop-start+1; x = x.levels[0].forward {
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// ZRangeByScore() returns the members of the sorted set stored at key with a score between
+// min and max, both included, ordered from the lowest to the highest score.
+// Use math.Inf() for an unbounded range.
+func (ks *KeyStore) ZRangeByScore(key string, min, max float64) ([]ZMember, error) {
+	members := []ZMember{}
+	err := readCollection(ks, key, func(z *keyStoreZSet) {
+		for x := z.zsl.firstFrom(min); x != nil && x.score <= max; x = x.levels[0].forward {
+			members = append(members, ZMember{Member: x.member, Score: x.score})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// ZIncrBy() atomically increments the score of a member of the sorted set stored at key by
+// delta and returns the new score. A missing key or member is created with a score of delta.
+// It fails with ErrNotNumeric if the score would be NaN.
+func (ks *KeyStore) ZIncrBy(key, member string, delta float64) (float64, error) {
+	var score float64
+	err := updateCollection(ks, key, newKeyStoreZSet, func(z *keyStoreZSet) error {
+		score = z.scores[member] + delta
+		if math.IsNaN(score) {
+			return ErrNotNumeric
+		}
+
+		z.set(member, score)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return score, nil
+}
+
+// newKeyStoreZSet returns an empty sorted set
+func newKeyStoreZSet() *keyStoreZSet {
+	return &keyStoreZSet{
+		scores: make(map[string]float64),
+		zsl:    newSkiplist(),
+	}
+}
+
+// len returns the number of members of the sorted set
+func (z *keyStoreZSet) len() int {
+	return len(z.scores)
+}
+
+// snapshot returns the members of the sorted set in order
+func (z *keyStoreZSet) snapshot() any {
+	members := make([]ZMember, 0, z.zsl.length)
+	for x := z.zsl.head.levels[0].forward; x != nil; x = x.levels[0].forward {
+		members = append(members, ZMember{Member: x.member, Score: x.score})
+	}
+
+	return members
+}
+
+// memSize returns the estimated bytes held by the sorted set
+func (z *keyStoreZSet) memSize() int64 {
+	return int64(unsafe.Sizeof(*z)) + int64(unsafe.Sizeof(*z.zsl)) + z.bytes
+}
+
+// set sets the score of a member and reports whether the member was added
+func (z *keyStoreZSet) set(member string, score float64) bool {
+	prev, exists := z.scores[member]
+	if exists {
+		if prev == score {
+			return false
+		}
+		z.zsl.delete(member, prev)
+	}
+
+	z.scores[member] = score
+	z.zsl.insert(member, score)
+	if !exists {
+		z.bytes += zsetMemberSize(member)
+	}
+
+	return !exists
+}
+
+// remove removes a member and reports whether it was in the sorted set
+func (z *keyStoreZSet) remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+
+	delete(z.scores, member)
+	z.zsl.delete(member, score)
+	z.bytes -= zsetMemberSize(member)
+
+	return true
+}
+
+// zsetMemberSize returns the estimated bytes held by a member of a sorted set: its entry
+// in the scores map and a skiplist node, assuming the average of 4/3 levels per node
+func zsetMemberSize(member string) int64 {
+	node := int64(unsafe.Sizeof(skiplistNode{})) + 4*int64(unsafe.Sizeof(skiplistLevel{}))/3
+	return stringSize(member) + int64(unsafe.Sizeof(float64(0))) + node
+}
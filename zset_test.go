@@ -0,0 +1,152 @@
+package fscache
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZAdd(t *testing.T) {
+	ks := newTestKeyStore()
+
+	added, err := ks.ZAdd("board",
+		ZMember{Member: "jane", Score: 30},
+		ZMember{Member: "john", Score: 10},
+		ZMember{Member: "bob", Score: 20},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 3, added)
+
+	// updating a score doesn't count as an addition
+	added, err = ks.ZAdd("board", ZMember{Member: "john", Score: 40}, ZMember{Member: "alice", Score: 20})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	members, err := ks.ZRange("board", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []ZMember{
+		{Member: "alice", Score: 20},
+		{Member: "bob", Score: 20},
+		{Member: "jane", Score: 30},
+		{Member: "john", Score: 40},
+	}, members)
+
+	score, err := ks.ZScore("board", "john")
+	require.NoError(t, err)
+	assert.Equal(t, float64(40), score)
+
+	rank, err := ks.ZRank("board", "jane")
+	require.NoError(t, err)
+	assert.Equal(t, 2, rank)
+
+	_, err = ks.ZScore("board", "missing")
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	_, err = ks.ZRank("missing", "jane")
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+
+	_, err = ks.ZAdd("board", ZMember{Member: "nan", Score: math.NaN()})
+	assert.ErrorIs(t, err, ErrNotNumeric)
+
+	// Get() returns the members in order
+	value, err := ks.Get("board")
+	require.NoError(t, err)
+	assert.Equal(t, members, value)
+}
+
+func TestZRange(t *testing.T) {
+	ks := newTestKeyStore()
+	for i := 0; i < 10; i++ {
+		_, err := ks.ZAdd("zset", ZMember{Member: string(rune('a' + i)), Score: float64(i)})
+		require.NoError(t, err)
+	}
+
+	members, err := ks.ZRange("zset", 2, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []ZMember{{"c", 2}, {"d", 3}, {"e", 4}}, members)
+
+	members, err = ks.ZRange("zset", -2, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []ZMember{{"i", 8}, {"j", 9}}, members)
+
+	members, err = ks.ZRange("zset", 20, 30)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	members, err = ks.ZRangeByScore("zset", 2.5, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []ZMember{{"d", 3}, {"e", 4}, {"f", 5}}, members)
+
+	members, err = ks.ZRangeByScore("zset", math.Inf(-1), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []ZMember{{"a", 0}, {"b", 1}}, members)
+
+	members, err = ks.ZRangeByScore("missing", math.Inf(-1), math.Inf(1))
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestZRem(t *testing.T) {
+	ks := newTestKeyStore()
+	before := ks.usage.bytes.Load()
+
+	_, err := ks.ZAdd("zset", ZMember{Member: "a", Score: 1}, ZMember{Member: "b", Score: 2})
+	require.NoError(t, err)
+
+	removed, err := ks.ZRem("zset", "a", "missing")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	rank, err := ks.ZRank("zset", "b")
+	require.NoError(t, err)
+	assert.Zero(t, rank)
+
+	// the key is deleted with its last member
+	removed, err = ks.ZRem("zset", "b")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, err = ks.Get("zset")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, before, ks.usage.bytes.Load())
+}
+
+func TestZIncrBy(t *testing.T) {
+	ks := newTestKeyStore()
+
+	score, err := ks.ZIncrBy("board", "jane", 5)
+	require.NoError(t, err)
+	assert.Equal(t, float64(5), score)
+
+	_, err = ks.ZAdd("board", ZMember{Member: "john", Score: 7})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ks.ZIncrBy("board", "jane", 0.5)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	score, err = ks.ZScore("board", "jane")
+	require.NoError(t, err)
+	assert.Equal(t, float64(55), score)
+
+	rank, err := ks.ZRank("board", "jane")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rank)
+
+	_, err = ks.ZAdd("board", ZMember{Member: "inf", Score: math.Inf(1)})
+	require.NoError(t, err)
+	_, err = ks.ZIncrBy("board", "inf", math.Inf(-1))
+	assert.ErrorIs(t, err, ErrNotNumeric)
+
+	_, err = ks.ZIncrBy("key1", "jane", 1)
+	assert.ErrorIs(t, err, ErrWrongType)
+}